@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AgentConfig makes this instance an agent: every status cycle is pushed
+// to Server, and start/stop requests made on the server are relayed back
+// over a long-poll. Name defaults to the host name.
+type AgentConfig struct {
+	Server string `json:"server"`
+	Name   string `json:"name,omitempty"`
+	Secret string `json:"secret"`
+}
+
+// AgentKey is an agent accepted by this (server) instance.
+type AgentKey struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// Agent requests carry these headers; the signature is hex HMAC-SHA256
+// over "name\ntimestamp\nnonce\nMETHOD endpoint\nbody" with the shared
+// secret. The endpoint is the last path element ("push", "commands",
+// "result"), so proxies that add or strip a path prefix do not matter. A nonce is accepted once, so a captured request cannot be
+// replayed while its timestamp is still within agentMaxSkew.
+const (
+	agentHeaderName  = "X-SPM-Agent"
+	agentHeaderTime  = "X-SPM-Timestamp"
+	agentHeaderNonce = "X-SPM-Nonce"
+	agentHeaderSig   = "X-SPM-Signature"
+	agentMaxSkew     = 5 * time.Minute
+	agentPollWait    = 25 * time.Second
+	agentRelayWait   = 30 * time.Second
+)
+
+func agentSignature(secret, name, ts, nonce, method, endpoint string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s %s\n", name, ts, nonce, method, endpoint)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nonces of accepted agent requests, kept until their timestamp can no
+// longer pass the skew check
+var agentNonces = struct {
+	sync.Mutex
+	seen map[string]time.Time // name + nonce -> expiry
+}{seen: map[string]time.Time{}}
+
+// agentNonceFresh records a nonce and reports whether it was unused.
+func agentNonceFresh(name, nonce string, now time.Time) bool {
+	agentNonces.Lock()
+	defer agentNonces.Unlock()
+	for k, exp := range agentNonces.seen {
+		if now.After(exp) {
+			delete(agentNonces.seen, k)
+		}
+	}
+	key := name + "\n" + nonce
+	if _, ok := agentNonces.seen[key]; ok {
+		return false
+	}
+	agentNonces.seen[key] = now.Add(2 * agentMaxSkew)
+	return true
+}
+
+// agentVerify checks the signature of an agent request to endpoint and
+// returns the agent name and the request body.
+func agentVerify(r *http.Request, endpoint string) (string, []byte, error) {
+	name := r.Header.Get(agentHeaderName)
+	var secret string
+	for _, a := range appCfg.Agents {
+		if a.Name == name && a.Secret != "" {
+			secret = a.Secret
+			break
+		}
+	}
+	if name == "" || secret == "" {
+		return "", nil, fmt.Errorf("unknown agent")
+	}
+	ts := r.Header.Get(agentHeaderTime)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("bad timestamp")
+	}
+	if d := time.Since(time.Unix(sec, 0)); d > agentMaxSkew || d < -agentMaxSkew {
+		return "", nil, fmt.Errorf("timestamp outside allowed skew")
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 8<<20))
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := r.Header.Get(agentHeaderNonce)
+	if len(nonce) < 16 || len(nonce) > 64 {
+		return "", nil, fmt.Errorf("bad nonce")
+	}
+	want := agentSignature(secret, name, ts, nonce, r.Method, endpoint, body)
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(agentHeaderSig))) {
+		return "", nil, fmt.Errorf("bad signature")
+	}
+	if !agentNonceFresh(name, nonce, time.Now()) {
+		return "", nil, fmt.Errorf("replayed request")
+	}
+	return name, body, nil
+}
+
+// agentCommand is a start/stop relayed from the server to an agent.
+type agentCommand struct {
+	ID      string    `json:"id"`
+	Action  string    `json:"action"` // start or stop
+	Service string    `json:"service"`
+	User    string    `json:"user"`
+	expires time.Time // the caller stops waiting; never deliver later
+}
+
+type agentResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// agentWaiter is a caller awaiting the result of a command sent to agent.
+type agentWaiter struct {
+	agent string
+	ch    chan agentResult
+}
+
+// server side: pending commands per agent and callers awaiting results
+var agentRelay = struct {
+	sync.Mutex
+	queues  map[string]chan agentCommand
+	waiting map[string]agentWaiter // command id
+}{queues: map[string]chan agentCommand{}, waiting: map[string]agentWaiter{}}
+
+func agentQueue(name string) chan agentCommand {
+	agentRelay.Lock()
+	defer agentRelay.Unlock()
+	q, ok := agentRelay.queues[name]
+	if !ok {
+		q = make(chan agentCommand, 16)
+		agentRelay.queues[name] = q
+	}
+	return q
+}
+
+// registerAgents lists configured agents on the dashboard before they
+// push for the first time.
+func registerAgents(keys []AgentKey) {
+	for _, a := range keys {
+		if a.Name == "" || a.Secret == "" {
+			log.Printf("agent ignored: name and secret are required (name=%q)", a.Name)
+			continue
+		}
+		peerRegister(a.Name, "agent", statusExportInterval)
+		agentQueue(a.Name)
+	}
+}
+
+// handleAgentPush serves POST /api/agent/push: a signed status document.
+func handleAgentPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name, body, err := agentVerify(r, "push")
+	if err != nil {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	doc, err := parseStatusDocument(body)
+	if err != nil {
+		respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad status document"})
+		return
+	}
+	peerUpdate(name, &doc, nil)
+	respondJSON(w, map[string]any{"ok": true})
+}
+
+// handleAgentCommands serves GET /api/agent/commands: waits up to
+// agentPollWait for a relayed command.
+func handleAgentCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name, _, err := agentVerify(r, "commands")
+	if err != nil {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	timer := time.NewTimer(agentPollWait)
+	defer timer.Stop()
+	q := agentQueue(name)
+	for {
+		select {
+		case cmd := <-q:
+			if time.Now().After(cmd.expires) {
+				continue
+			}
+			respondJSON(w, map[string]any{"commands": []agentCommand{cmd}})
+		case <-timer.C:
+			respondJSON(w, map[string]any{"commands": []agentCommand{}})
+		case <-r.Context().Done():
+		}
+		return
+	}
+}
+
+// handleAgentResult serves POST /api/agent/result for a relayed command.
+// Only the agent the command was sent to may answer it.
+func handleAgentResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name, body, err := agentVerify(r, "result")
+	if err != nil {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	var res agentResult
+	if err := json.Unmarshal(body, &res); err != nil {
+		respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad json"})
+		return
+	}
+	agentRelay.Lock()
+	wt, ok := agentRelay.waiting[res.ID]
+	if ok && wt.agent != name {
+		agentRelay.Unlock()
+		respondJSONCode(w, http.StatusForbidden, map[string]string{"error": "command belongs to another agent"})
+		return
+	}
+	delete(agentRelay.waiting, res.ID)
+	agentRelay.Unlock()
+	if ok {
+		wt.ch <- res
+	}
+	respondJSON(w, map[string]any{"ok": ok})
+}
+
+// relayAgentAction queues a start/stop for an agent and waits for its
+// answer. The agent applies its own control flags.
+func relayAgentAction(w http.ResponseWriter, r *http.Request, agent, kind, service, user string) {
+	agentRelay.Lock()
+	q, ok := agentRelay.queues[agent]
+	agentRelay.Unlock()
+	if !ok {
+		respondJSONCode(w, http.StatusNotFound, map[string]string{"error": "agent not found"})
+		return
+	}
+	cmd := agentCommand{ID: newToken()[:12], Action: kind, Service: service, User: user, expires: time.Now().Add(agentRelayWait)}
+	ch := make(chan agentResult, 1)
+	agentRelay.Lock()
+	agentRelay.waiting[cmd.ID] = agentWaiter{agent: agent, ch: ch}
+	agentRelay.Unlock()
+	defer func() {
+		agentRelay.Lock()
+		delete(agentRelay.waiting, cmd.ID)
+		agentRelay.Unlock()
+	}()
+
+	result := "ok"
+	select {
+	case q <- cmd:
+		timer := time.NewTimer(agentRelayWait)
+		defer timer.Stop()
+		select {
+		case res := <-ch:
+			if !res.OK {
+				result = "error: " + res.Error
+			}
+		case <-timer.C:
+			result = "error: agent did not answer"
+		}
+	default:
+		result = "error: agent command queue full"
+	}
+	_ = logAction(appCfg.LogFile, time.Now(), user, clientIP(r), &ServiceInfo{Name: agent + "/" + service}, kind, result)
+	if result != "ok" {
+		respondJSONCode(w, http.StatusBadGateway, map[string]any{"ok": false, "error": strings.TrimPrefix(result, "error: ")})
+		return
+	}
+	respondJSON(w, map[string]any{"ok": true})
+}
+
+// agent side
+
+type agentClient struct {
+	cfg    AgentConfig
+	base   string
+	host   string // server host, recorded as the IP of relayed actions
+	client *http.Client
+	outbox chan []Service // latest snapshot only
+}
+
+var agentLink *agentClient
+
+// do sends a signed request to /api/agent/<endpoint> below the server URL.
+func (a *agentClient) do(method, endpoint string, body []byte, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequest(method, a.base+"/api/agent/"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := newToken()
+	req.Header.Set(agentHeaderName, a.cfg.Name)
+	req.Header.Set(agentHeaderTime, ts)
+	req.Header.Set(agentHeaderNonce, nonce)
+	req.Header.Set(agentHeaderSig, agentSignature(a.cfg.Secret, a.cfg.Name, ts, nonce, method, endpoint, body))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c := *a.client
+	c.Timeout = timeout
+	return c.Do(req)
+}
+
+// startAgent pushes status snapshots and executes relayed commands.
+func startAgent(cfg AgentConfig, services []ServiceInfo, statusPath string) {
+	if cfg.Server == "" || cfg.Secret == "" {
+		log.Println("agent mode disabled: server and secret are required")
+		return
+	}
+	if cfg.Name == "" {
+		cfg.Name = monitorHost
+	}
+	a := &agentClient{cfg: cfg, base: strings.TrimRight(cfg.Server, "/"), client: &http.Client{}, outbox: make(chan []Service, 1)}
+	if u, err := url.Parse(a.base); err == nil {
+		a.host = u.Hostname()
+	}
+	agentLink = a
+	log.Printf("agent mode: pushing to %s as %q", a.base, cfg.Name)
+	go a.pushLoop()
+	go a.commandLoop(services, statusPath)
+}
+
+// queueAgentPush hands the latest cycle to the pusher, replacing an
+// unsent older snapshot.
+func queueAgentPush(curr []Service) {
+	if agentLink == nil {
+		return
+	}
+	snap := append([]Service(nil), curr...)
+	select {
+	case <-agentLink.outbox:
+	default:
+	}
+	agentLink.outbox <- snap
+}
+
+func (a *agentClient) pushLoop() {
+	var failing bool
+	for snap := range a.outbox {
+		body, err := json.Marshal(newStatusDocument(snap, time.Now()))
+		if err != nil {
+			continue
+		}
+		resp, err := a.do(http.MethodPost, "push", body, 10*time.Second)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("server answered %d", resp.StatusCode)
+			}
+		}
+		if err != nil && !failing {
+			log.Printf("agent push failed: %v", err)
+		} else if err == nil && failing {
+			log.Printf("agent push recovered")
+		}
+		failing = err != nil
+	}
+}
+
+func (a *agentClient) commandLoop(services []ServiceInfo, statusPath string) {
+	for {
+		resp, err := a.do(http.MethodGet, "commands", nil, agentPollWait+15*time.Second)
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		var out struct {
+			Commands []agentCommand `json:"commands"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for _, cmd := range out.Commands {
+			res := a.execute(cmd, services)
+			body, _ := json.Marshal(res)
+			if resp, err := a.do(http.MethodPost, "result", body, 10*time.Second); err == nil {
+				resp.Body.Close()
+			}
+			if res.OK {
+				_ = refreshStatusFile(services, statusPath)
+			}
+		}
+	}
+}
+
+// execute runs a relayed command under this agent's own control flags.
+func (a *agentClient) execute(cmd agentCommand, services []ServiceInfo) agentResult {
+	res := agentResult{ID: cmd.ID}
+	if cmd.Action != "start" && cmd.Action != "stop" {
+		res.Error = "unknown action"
+		return res
+	}
+	for _, si := range services {
+		if !strings.EqualFold(si.Name, cmd.Service) {
+			continue
+		}
+		if code, msg := controlAllowed(si, cmd.Action); code != 0 {
+			res.Error = msg
+			return res
+		}
+		if err := performAction(si, cmd.Action, cmd.User, a.host); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.OK = true
+		return res
+	}
+	res.Error = "service not found"
+	return res
+}
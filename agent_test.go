@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func setupAgentKeys(t *testing.T) {
+	t.Helper()
+	prev := appCfg
+	appCfg = &Config{Agents: []AgentKey{{Name: "a1", Secret: "secret-1"}, {Name: "a2", Secret: "secret-2"}}}
+	t.Cleanup(func() { appCfg = prev })
+}
+
+// agentRequest builds a request to endpoint (mounted under a proxy prefix)
+// signed over signed; the body sent is body.
+func agentRequest(name, secret, method, endpoint string, signed, body []byte, ts time.Time, nonce string) *http.Request {
+	r := httptest.NewRequest(method, "/monitor/api/agent/"+endpoint, bytes.NewReader(body))
+	sec := strconv.FormatInt(ts.Unix(), 10)
+	r.Header.Set(agentHeaderName, name)
+	r.Header.Set(agentHeaderTime, sec)
+	r.Header.Set(agentHeaderNonce, nonce)
+	r.Header.Set(agentHeaderSig, agentSignature(secret, name, sec, nonce, method, endpoint, signed))
+	return r
+}
+
+func TestAgentVerify(t *testing.T) {
+	setupAgentKeys(t)
+	body := []byte(`{"services":[]}`)
+	now := time.Now()
+	tests := []struct {
+		name    string
+		req     *http.Request
+		verify  string // endpoint the handler checks
+		wantErr string
+	}{
+		{"valid", agentRequest("a1", "secret-1", http.MethodPost, "push", body, body, now, newToken()), "push", ""},
+		{"tampered body", agentRequest("a1", "secret-1", http.MethodPost, "push", body, []byte(`{"services":[{}]}`), now, newToken()), "push", "bad signature"},
+		{"other endpoint", agentRequest("a1", "secret-1", http.MethodPost, "push", body, body, now, newToken()), "result", "bad signature"},
+		{"wrong secret", agentRequest("a1", "secret-2", http.MethodPost, "push", body, body, now, newToken()), "push", "bad signature"},
+		{"unknown agent", agentRequest("a3", "secret-1", http.MethodPost, "push", body, body, now, newToken()), "push", "unknown agent"},
+		{"stale timestamp", agentRequest("a1", "secret-1", http.MethodPost, "push", body, body, now.Add(-agentMaxSkew-time.Minute), newToken()), "push", "timestamp outside allowed skew"},
+		{"future timestamp", agentRequest("a1", "secret-1", http.MethodPost, "push", body, body, now.Add(agentMaxSkew+time.Minute), newToken()), "push", "timestamp outside allowed skew"},
+		{"short nonce", agentRequest("a1", "secret-1", http.MethodPost, "push", body, body, now, "abc"), "push", "bad nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, got, err := agentVerify(tt.req, tt.verify)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if name != "a1" || !bytes.Equal(got, body) {
+				t.Fatalf("got agent %q body %q", name, got)
+			}
+		})
+	}
+}
+
+func TestAgentVerifyReplay(t *testing.T) {
+	setupAgentKeys(t)
+	nonce := newToken()
+	now := time.Now()
+	if _, _, err := agentVerify(agentRequest("a1", "secret-1", http.MethodGet, "commands", nil, nil, now, nonce), "commands"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	_, _, err := agentVerify(agentRequest("a1", "secret-1", http.MethodGet, "commands", nil, nil, now, nonce), "commands")
+	if err == nil || err.Error() != "replayed request" {
+		t.Fatalf("replay: err = %v, want replayed request", err)
+	}
+	// the same nonce from another agent is a different request
+	if _, _, err := agentVerify(agentRequest("a2", "secret-2", http.MethodGet, "commands", nil, nil, now, nonce), "commands"); err != nil {
+		t.Fatalf("other agent: %v", err)
+	}
+}
+
+func TestAgentResultOwner(t *testing.T) {
+	setupAgentKeys(t)
+	ch := make(chan agentResult, 1)
+	agentRelay.Lock()
+	agentRelay.waiting["cmd-1"] = agentWaiter{agent: "a1", ch: ch}
+	agentRelay.Unlock()
+	t.Cleanup(func() {
+		agentRelay.Lock()
+		delete(agentRelay.waiting, "cmd-1")
+		agentRelay.Unlock()
+	})
+	body := []byte(`{"id":"cmd-1","ok":true}`)
+
+	w := httptest.NewRecorder()
+	handleAgentResult(w, agentRequest("a2", "secret-2", http.MethodPost, "result", body, body, time.Now(), newToken()))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("wrong agent: status %d, want 403", w.Code)
+	}
+	select {
+	case res := <-ch:
+		t.Fatalf("wrong agent delivered %+v", res)
+	default:
+	}
+
+	w = httptest.NewRecorder()
+	handleAgentResult(w, agentRequest("a1", "secret-1", http.MethodPost, "result", body, body, time.Now(), newToken()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("owner: status %d, want 200", w.Code)
+	}
+	select {
+	case res := <-ch:
+		if !res.OK || res.ID != "cmd-1" {
+			t.Fatalf("got %+v", res)
+		}
+	default:
+		t.Fatal("owner result not delivered")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertPolicy keeps paging while a service stays down and nobody has
+// acknowledged the incident: RemindEvery repeats the down alert (at most
+// MaxReminders times, 0 = unlimited) and EscalateAfter sends it once to
+// the EscalateTo notifiers, which then also get the reminders. The first
+// policy matching the service by name or tag applies; a policy with
+// neither matches every service.
+type AlertPolicy struct {
+	Name          string   `json:"name"`
+	Services      []string `json:"services,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	RemindEvery   string   `json:"remind_every,omitempty"`
+	MaxReminders  int      `json:"max_reminders,omitempty"`
+	EscalateAfter string   `json:"escalate_after,omitempty"`
+	EscalateTo    []string `json:"escalate_to,omitempty"`
+}
+
+func (p AlertPolicy) matches(si ServiceInfo) bool {
+	if len(p.Services) == 0 && len(p.Tags) == 0 {
+		return true
+	}
+	return containsFold(p.Services, si.Name) || hasAnyTag(si.Tags, p.Tags)
+}
+
+// alertState is the paging progress of one open incident; reminders and
+// escalation are also stored on the incident.
+type alertState struct {
+	policy      string
+	last        time.Time // last down alert or reminder
+	reminders   int
+	escalatedAt *time.Time
+}
+
+const alertTick = 5 * time.Second
+
+// alert policies and the state of every open incident they cover
+var alerts = struct {
+	sync.Mutex
+	policies []AlertPolicy
+	started  time.Time
+	state    map[string]*alertState // by incident ID
+}{state: map[string]*alertState{}}
+
+// startAlerts runs the reminder/escalation loop.
+func startAlerts(policies []AlertPolicy) {
+	alerts.Lock()
+	alerts.started = time.Now()
+	for _, p := range policies {
+		if parseDurationDefault(p.RemindEvery, 0) == 0 && parseDurationDefault(p.EscalateAfter, 0) == 0 {
+			log.Printf("alert policy %s ignored: neither remind_every nor escalate_after set", p.Name)
+			continue
+		}
+		alerts.policies = append(alerts.policies, p)
+	}
+	n := len(alerts.policies)
+	alerts.Unlock()
+	if n == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(alertTick)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			runAlertPolicies(now)
+		}
+	}()
+}
+
+func alertPolicyFor(si ServiceInfo) *AlertPolicy {
+	for i := range alerts.policies {
+		if alerts.policies[i].matches(si) {
+			return &alerts.policies[i]
+		}
+	}
+	return nil
+}
+
+// runAlertPolicies sends due reminders and escalations for open,
+// unacknowledged incidents and forgets closed ones.
+func runAlertPolicies(now time.Time) {
+	alerts.Lock()
+	defer alerts.Unlock()
+	open := map[string]bool{}
+	for _, in := range incidents.active() {
+		open[in.ID] = true
+		si := notifyServiceInfo(in.Service)
+		p := alertPolicyFor(si)
+		if p == nil || in.AckedAt != nil {
+			continue
+		}
+		// incidents older than this process had their alert before a
+		// restart; they keep the reminders and escalation already sent and
+		// the rest counts from the restart
+		since := in.Start
+		if since.Before(alerts.started) {
+			since = alerts.started
+		}
+		st := alerts.state[in.ID]
+		if st == nil {
+			st = &alertState{policy: p.Name, last: since, reminders: in.Reminders, escalatedAt: in.EscalatedAt}
+			alerts.state[in.ID] = st
+		}
+		ev := Event{Type: "down", Service: in.Service, State: "down", Reason: in.Reason, Tags: si.Tags, Link: si.Link, Image: si.Image,
+			Time: now, Duration: int64(now.Sub(in.Start).Seconds()), IncidentID: in.ID}
+		if after := parseDurationDefault(p.EscalateAfter, 0); after > 0 && st.escalatedAt == nil && now.Sub(since) >= after {
+			at := now
+			st.escalatedAt = &at
+			incidents.recordAlert(in.ID, st.reminders, st.escalatedAt, now)
+			esc := ev
+			esc.Escalated = true
+			dispatch(esc, func(n *notifier) bool { return containsFold(p.EscalateTo, n.cfg.Name) })
+			log.Printf("alert %s escalated to %s", in.ID, strings.Join(p.EscalateTo, ", "))
+		}
+		every := parseDurationDefault(p.RemindEvery, 0)
+		if every == 0 || now.Sub(st.last) < every || (p.MaxReminders > 0 && st.reminders >= p.MaxReminders) {
+			continue
+		}
+		st.reminders++
+		st.last = now
+		incidents.recordAlert(in.ID, st.reminders, st.escalatedAt, now)
+		ev.Reminder = st.reminders
+		ev = prepareEvent(ev)
+		targets := routeEvent(ev).Targets
+		escalated := st.escalatedAt != nil
+		dispatch(ev, func(n *notifier) bool {
+			return n.accepts(ev, targets) || (escalated && containsFold(p.EscalateTo, n.cfg.Name))
+		})
+	}
+	for id := range alerts.state {
+		if !open[id] {
+			delete(alerts.state, id)
+		}
+	}
+}
+
+// notifyAck tells notifiers (those listing "ack") who took an incident.
+func notifyAck(in Incident, user string, now time.Time) {
+	si := notifyServiceInfo(in.Service)
+	notify(Event{Type: "ack", Service: in.Service, State: "down", Tags: si.Tags, Link: si.Link, Image: si.Image,
+		Time: now, IncidentID: in.ID, User: user})
+}
+
+// handleAlerts serves GET /api/alerts (open incidents with their paging
+// state) and POST /api/alerts/{id}/ack. Both require a login.
+func handleAlerts(w http.ResponseWriter, r *http.Request) {
+	user := authUser(r)
+	if user == "" {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/alerts"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		respondJSON(w, map[string]any{"alerts": alertList(time.Now())})
+		return
+	}
+	id, ok := strings.CutSuffix(rest, "/ack")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	in, acked, err := ackIncident(id, user, clientIP(r))
+	if err != nil {
+		respondJSONCode(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	respondJSON(w, map[string]any{"ok": true, "already_acked": !acked, "incident": in})
+}
+
+type alertJSON struct {
+	IncidentID string     `json:"incident_id"`
+	Service    string     `json:"service"`
+	Start      time.Time  `json:"start"`
+	DownFor    int64      `json:"down_for_seconds"`
+	Reason     string     `json:"reason,omitempty"`
+	Policy     string     `json:"policy,omitempty"`
+	Reminders  int        `json:"reminders"`
+	Escalated  bool       `json:"escalated"`
+	AckedBy    string     `json:"acked_by,omitempty"`
+	AckedAt    *time.Time `json:"acked_at,omitempty"`
+}
+
+func alertList(now time.Time) []alertJSON {
+	alerts.Lock()
+	defer alerts.Unlock()
+	res := []alertJSON{}
+	for _, in := range incidents.active() {
+		a := alertJSON{IncidentID: in.ID, Service: in.Service, Start: in.Start, DownFor: int64(now.Sub(in.Start).Seconds()),
+			Reason: in.Reason, AckedBy: in.AckedBy, AckedAt: in.AckedAt}
+		if p := alertPolicyFor(notifyServiceInfo(in.Service)); p != nil {
+			a.Policy = p.Name
+		}
+		a.Reminders, a.Escalated = in.Reminders, in.EscalatedAt != nil
+		if st := alerts.state[in.ID]; st != nil {
+			a.Reminders, a.Escalated = st.reminders, st.escalatedAt != nil
+		}
+		res = append(res, a)
+	}
+	return res
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// badge colours (shields.io palette)
+const (
+	badgeGreen     = "#4c1"
+	badgeLightGood = "#97ca00"
+	badgeYellow    = "#dfb317"
+	badgeRed       = "#e05d44"
+	badgeBlue      = "#007ec6"
+	badgeGrey      = "#9f9f9f"
+)
+
+var badgeStateColor = map[string]string{
+	"up":          badgeGreen,
+	"degraded":    badgeYellow,
+	"down":        badgeRed,
+	"maintenance": badgeBlue,
+}
+
+func uptimeColor(pct float64) string {
+	switch {
+	case pct >= 99.9:
+		return badgeGreen
+	case pct >= 99:
+		return badgeLightGood
+	case pct >= 95:
+		return badgeYellow
+	default:
+		return badgeRed
+	}
+}
+
+// badgeTextWidth approximates Verdana 11px, which is what shields uses.
+func badgeTextWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljtf.,:;|!' ", r):
+			w += 4
+		case strings.ContainsRune("mwMW%", r):
+			w += 10
+		case r >= 'A' && r <= 'Z', r > 127:
+			w += 8
+		default:
+			w += 7
+		}
+	}
+	return w
+}
+
+// renderBadge draws a flat two-part badge: grey label, coloured value.
+func renderBadge(label, value, color string) []byte {
+	lw := badgeTextWidth(label) + 10
+	vw := badgeTextWidth(value) + 10
+	total := lw + vw
+	l, v := html.EscapeString(label), html.EscapeString(value)
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text></g></svg>`,
+		total, lw, vw, l, v, color, lw/2, lw+vw/2))
+}
+
+// findBadgeService matches the path segment against the service name
+// (case-insensitive) or its slug, e.g. "my-app" for "My App".
+func findBadgeService(services []Service, id string) (Service, bool) {
+	for _, s := range services {
+		if strings.EqualFold(s.Name, id) || serviceSlug(s.Name) == id {
+			return s, true
+		}
+	}
+	return Service{}, false
+}
+
+func isPrivateService(infos []ServiceInfo, name string) bool {
+	for _, si := range infos {
+		if si.Name == name {
+			return si.Private
+		}
+	}
+	return false
+}
+
+// privateVisible reports whether r may see private services: it has a
+// login session or sends metrics_token as Bearer token (peers, scrapers).
+func privateVisible(r *http.Request) bool {
+	if authUser(r) != "" {
+		return true
+	}
+	tok := appCfg.MetricsToken
+	return tok != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+tok)) == 1
+}
+
+func hasPrivateServices(infos []ServiceInfo) bool {
+	for _, si := range infos {
+		if si.Private {
+			return true
+		}
+	}
+	return false
+}
+
+// publicServices drops the private services from services.
+func publicServices(services []Service, infos []ServiceInfo) []Service {
+	res := make([]Service, 0, len(services))
+	for _, s := range services {
+		if !isPrivateService(infos, s.Name) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// badgeHandler serves /badge/{service}.svg (current state) and
+// /badge/{service}/uptime.svg?window=24h|7d|30d|90d (default 30d).
+// Private services are only rendered for logged-in users; everyone else
+// gets the same 404 as for an unknown name.
+func badgeHandler(infos []ServiceInfo, statusPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.EscapedPath(), "/badge/")
+		kind := "status"
+		switch {
+		case strings.HasSuffix(rest, "/uptime.svg"):
+			rest, kind = strings.TrimSuffix(rest, "/uptime.svg"), "uptime"
+		case strings.HasSuffix(rest, ".svg"):
+			rest = strings.TrimSuffix(rest, ".svg")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		id, err := url.PathUnescape(rest)
+		if err != nil || id == "" || !utf8.ValidString(id) {
+			http.NotFound(w, r)
+			return
+		}
+		services, err := loadStatusFile(statusPath)
+		if err != nil {
+			services = defaultServicesFromInfo(infos)
+		}
+		s, ok := findBadgeService(services, id)
+		if !ok || (isPrivateService(infos, s.Name) && authUser(r) == "") {
+			http.NotFound(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		label := q.Get("label")
+		var value, color string
+		if kind == "status" {
+			if label == "" {
+				label = s.Name
+			}
+			value = s.State
+			if value == "" {
+				value = map[bool]string{true: "up", false: "down"}[s.Active]
+			}
+			color = badgeStateColor[value]
+		} else {
+			win := q.Get("window")
+			if win == "" {
+				win = "30d"
+			}
+			if label == "" {
+				label = "uptime " + win
+			}
+			if pct, ok := s.Uptime[win]; ok {
+				value, color = formatUptime(pct), uptimeColor(pct)
+			} else {
+				value, color = "n/a", badgeGrey
+			}
+		}
+		if color == "" {
+			color = badgeGrey
+		}
+		body := renderBadge(label, value, color)
+
+		h := fnv.New64a()
+		h.Write(body)
+		etag := fmt.Sprintf(`"%x"`, h.Sum64())
+		maxAge := int(statusExportInterval / time.Second)
+		if maxAge < 5 {
+			maxAge = 5
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, s-maxage=%d, must-revalidate", maxAge, maxAge))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		_, _ = w.Write(body)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func checkCertFiles(cc *CertCheck) checkResult {
+	if cc == nil || len(cc.Paths) == 0 {
+		return checkResult{Reason: "cert check has no paths"}
+	}
+	warnDays := cc.WarnDays
+	if warnDays <= 0 {
+		warnDays = 30
+	}
+	critDays := cc.CriticalDays
+	if critDays <= 0 {
+		critDays = 7
+	}
+
+	var earliest *x509.Certificate
+	var earliestFile string
+	total := 0
+	for _, p := range cc.Paths {
+		if !filepath.IsAbs(p) {
+			p = resolvePath(p)
+		}
+		files, err := filepath.Glob(p)
+		if err != nil {
+			return checkResult{Reason: fmt.Sprintf("bad pattern %s: %v", p, err)}
+		}
+		if len(files) == 0 {
+			return checkResult{Reason: "no certificate at " + p}
+		}
+		for _, f := range files {
+			certs, err := readPEMCerts(f)
+			if err != nil {
+				return checkResult{Reason: err.Error()}
+			}
+			for _, c := range certs {
+				total++
+				if earliest == nil || c.NotAfter.Before(earliest.NotAfter) {
+					earliest, earliestFile = c, f
+				}
+			}
+		}
+	}
+
+	days := int(time.Until(earliest.NotAfter).Hours() / 24)
+	subject := earliest.Subject.CommonName
+	if subject == "" {
+		subject = earliest.Subject.String()
+	}
+	res := checkResult{Active: true, Detail: fmt.Sprintf("%s: %dd left (%s)", subject, days, earliest.NotAfter.Format("2006-01-02"))}
+	switch {
+	case time.Now().After(earliest.NotAfter):
+		res.Active = false
+		res.Reason = fmt.Sprintf("%s in %s expired on %s", subject, filepath.Base(earliestFile), earliest.NotAfter.Format("2006-01-02"))
+	case days < critDays:
+		res.Active = false
+		res.Reason = fmt.Sprintf("%s in %s expires in %d days", subject, filepath.Base(earliestFile), days)
+	case days < warnDays:
+		res.Degraded = true
+		res.Reason = fmt.Sprintf("%s expires in %d days", subject, days)
+	}
+	if total > 1 {
+		res.Detail += fmt.Sprintf(", %d certs", total)
+	}
+	return res
+}
+
+// readPEMCerts returns every CERTIFICATE block in a PEM file; keys and
+// other blocks are skipped.
+func readPEMCerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(path), err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s: no PEM certificates", filepath.Base(path))
+	}
+	return certs, nil
+}
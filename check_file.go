@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func checkFile(fc *FileCheck) checkResult {
+	if fc == nil || fc.Path == "" {
+		return checkResult{Reason: "file check has no path"}
+	}
+	pattern := fc.Path
+	if !filepath.IsAbs(pattern) {
+		pattern = resolvePath(pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return checkResult{Reason: fmt.Sprintf("bad pattern: %v", err)}
+	}
+	var newest os.FileInfo
+	count := 0
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		count++
+		if newest == nil || fi.ModTime().After(newest.ModTime()) {
+			newest = fi
+		}
+	}
+	if newest == nil {
+		return checkResult{Reason: "no files match " + fc.Path}
+	}
+
+	age := time.Since(newest.ModTime())
+	res := checkResult{Detail: fmt.Sprintf("newest %s ago, %s", formatAge(age), formatBytes(newest.Size()))}
+	if count > 1 {
+		res.Detail += fmt.Sprintf(", %d files", count)
+	}
+	maxAge := parseDurationDefault(fc.MaxAge, 0)
+	switch {
+	case maxAge > 0 && age > maxAge:
+		res.Reason = fmt.Sprintf("%s is %s old (max %s)", newest.Name(), formatAge(age), maxAge)
+	case fc.MinSize > 0 && newest.Size() < fc.MinSize:
+		res.Reason = fmt.Sprintf("%s is %s (min %s)", newest.Name(), formatBytes(newest.Size()), formatBytes(fc.MinSize))
+	case fc.ExpectCount > 0 && count < fc.ExpectCount:
+		res.Reason = fmt.Sprintf("%d files match, expected %d", count, fc.ExpectCount)
+	default:
+		res.Active = true
+	}
+	return res
+}
+
+// formatAge renders a duration at a human granularity (45s, 12m, 5h, 3d).
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
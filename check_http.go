@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// max body bytes read per step (assertions + captures)
+const httpCheckBodyLimit = 1 << 20
+
+var httpVarRe = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// checkHTTP runs the configured steps in order and stops at the first failure.
+// Reason names the failed step; Latency is the total duration.
+func checkHTTP(hc *HTTPCheck) checkResult {
+	if hc == nil || len(hc.Steps) == 0 {
+		return checkResult{Reason: "http check has no steps"}
+	}
+	timeout := parseDurationDefault(hc.Timeout, 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	vars := map[string]string{}
+	start := time.Now()
+	for i, step := range hc.Steps {
+		if err := runHTTPStep(ctx, client, step, vars); err != nil {
+			name := step.Name
+			if name == "" {
+				name = step.URL
+			}
+			return checkResult{Reason: fmt.Sprintf("step %d (%s): %v", i+1, name, err), Latency: time.Since(start)}
+		}
+	}
+	return checkResult{Active: true, Latency: time.Since(start)}
+}
+
+func runHTTPStep(ctx context.Context, client *http.Client, step HTTPStep, vars map[string]string) error {
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expandVars(step.Body, vars))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, expandVars(step.URL, vars), body)
+	if err != nil {
+		return err
+	}
+	for k, v := range step.Headers {
+		req.Header.Set(k, expandVars(v, vars))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, httpCheckBodyLimit))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	want := step.ExpectStatus
+	if want == 0 {
+		if resp.StatusCode < 200 || resp.StatusCode > 399 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+	} else if resp.StatusCode != want {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, want)
+	}
+	if step.ExpectContains != "" {
+		needle := expandVars(step.ExpectContains, vars)
+		if !strings.Contains(string(data), needle) {
+			return fmt.Errorf("body does not contain %q", needle)
+		}
+	}
+	for name, expr := range step.Capture {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("capture %s: %v", name, err)
+		}
+		m := re.FindSubmatch(data)
+		if m == nil {
+			return fmt.Errorf("capture %s: no match", name)
+		}
+		if len(m) > 1 {
+			vars[name] = string(m[1])
+		} else {
+			vars[name] = string(m[0])
+		}
+	}
+	return nil
+}
+
+// expandVars replaces ${name} with captured values; unknown names are kept as-is.
+func expandVars(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "${") {
+		return s
+	}
+	return httpVarRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[m[2:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
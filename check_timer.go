@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkSystemdTimer reports a timer (service_name/systemd_name, ".timer"
+// appended when missing) as down when the timer is not active or the last
+// run of its service did not succeed. Between runs a oneshot unit is
+// inactive, so the service's Result is used instead of is-active.
+func checkSystemdTimer(s ServiceInfo) checkResult {
+	if runtime.GOOS != "linux" {
+		return checkResult{Reason: "systemd timers are only supported on linux"}
+	}
+	timer := s.ServiceName
+	if timer == "" {
+		timer = s.SystemdName
+	}
+	if timer == "" {
+		return checkResult{Reason: "systemd_timer needs service_name or systemd_name"}
+	}
+	if !strings.Contains(timer, ".") {
+		timer += ".timer"
+	}
+	tp, err := systemctlShowTimes(timer, "ActiveState", "LastTriggerUSec", "NextElapseUSecRealtime", "NextElapseUSecMonotonic", "Unit")
+	if err != nil {
+		return checkResult{Reason: err.Error()}
+	}
+	unit := tp["Unit"]
+	if unit == "" {
+		unit = strings.TrimSuffix(timer, ".timer") + ".service"
+	}
+	sp, err := systemctlShow(unit, "ActiveState", "Result")
+	if err != nil {
+		return checkResult{Reason: err.Error()}
+	}
+
+	now := time.Now()
+	var parts []string
+	if last, ok := parseSystemdTime(tp["LastTriggerUSec"]); ok {
+		parts = append(parts, "last "+formatAge(now.Sub(last))+" ago")
+	} else {
+		parts = append(parts, "never ran")
+	}
+	if next, ok := parseSystemdTime(tp["NextElapseUSecRealtime"]); ok {
+		parts = append(parts, "next in "+formatAge(next.Sub(now)))
+	} else if next, ok := nextMonotonic(tp["NextElapseUSecMonotonic"]); ok {
+		parts = append(parts, "next in "+formatAge(next))
+	}
+	if sp["ActiveState"] == "activating" || sp["ActiveState"] == "active" {
+		parts = append(parts, "running")
+	}
+	res := checkResult{Detail: strings.Join(parts, ", ")}
+	switch {
+	case tp["ActiveState"] != "active":
+		res.Reason = fmt.Sprintf("timer %s is %s", timer, tp["ActiveState"])
+	case sp["Result"] != "" && sp["Result"] != "success":
+		res.Reason = fmt.Sprintf("last run of %s failed: %s", unit, sp["Result"])
+	default:
+		res.Active = true
+	}
+	return res
+}
+
+// systemctlShow returns the requested unit properties as a map.
+func systemctlShow(unit string, props ...string) (map[string]string, error) {
+	return runSystemctlShow(nil, nil, unit, props)
+}
+
+// systemctlShowTimes is systemctlShow for timestamp properties, which are
+// printed as "@<unix seconds>" (systemd 247+). Older versions reject the
+// option; they get the C locale and UTC so the formatted time parses.
+func systemctlShowTimes(unit string, props ...string) (map[string]string, error) {
+	if m, err := runSystemctlShow([]string{"--timestamp=unix"}, nil, unit, props); err == nil {
+		return m, nil
+	}
+	return runSystemctlShow(nil, []string{"LC_ALL=C", "TZ=UTC"}, unit, props)
+}
+
+func runSystemctlShow(flags, env []string, unit string, props []string) (map[string]string, error) {
+	args := append([]string{"show"}, flags...)
+	args = append(args, unit)
+	for _, p := range props {
+		args = append(args, "-p", p)
+	}
+	cmd := exec.Command("systemctl", args...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show %s: %v", unit, err)
+	}
+	m := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+// nextMonotonic turns a timer's NextElapseUSecMonotonic (time since boot,
+// set for OnBootSec/OnUnitActiveSec timers) into the time left. Uptime
+// includes suspend while the monotonic clock does not, so after a suspend
+// the estimate is early.
+func nextMonotonic(v string) (time.Duration, bool) {
+	at, ok := parseSystemdSpan(v)
+	if !ok || at == 0 {
+		return 0, false
+	}
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, false
+	}
+	f := strings.Fields(string(data))
+	if len(f) == 0 {
+		return 0, false
+	}
+	up, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return max(at-time.Duration(up*float64(time.Second)), 0), true
+}
+
+// parseSystemdSpan accepts raw microseconds and systemd timespans such as
+// "1d 2h 30min 4.5s".
+func parseSystemdSpan(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" || v == "infinity" {
+		return 0, false
+	}
+	if usec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(usec) * time.Microsecond, true
+	}
+	units := map[string]time.Duration{
+		"us": time.Microsecond, "ms": time.Millisecond, "s": time.Second, "min": time.Minute,
+		"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+		"M": 2629800 * time.Second, "y": 31557600 * time.Second,
+	}
+	var total time.Duration
+	for _, f := range strings.Fields(v) {
+		i := strings.IndexFunc(f, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(f[:i], 64)
+		unit, ok := units[f[i:]]
+		if err != nil || !ok {
+			return 0, false
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	return total, true
+}
+
+// parseSystemdTime accepts "@<unix>" (--timestamp=unix), raw microseconds
+// and the C-locale format of older systemctl ("Sat 2026-10-17 03:00:00 UTC").
+func parseSystemdTime(v string) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" || v == "n/a" || v == "0" {
+		return time.Time{}, false
+	}
+	if strings.HasPrefix(v, "@") {
+		if sec, err := strconv.ParseInt(v[1:], 10, 64); err == nil {
+			return time.Unix(sec, 0), true
+		}
+	}
+	if usec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMicro(usec), true
+	}
+	if t, err := time.Parse("Mon 2006-01-02 15:04:05 MST", v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
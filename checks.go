@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkResult is the outcome of a typed check.
+type checkResult struct {
+	Active   bool
+	Degraded bool // up, but past a warning threshold
+	Reason   string
+	Detail   string // short summary shown on the card
+	Latency  time.Duration
+}
+
+// runTypedCheck dispatches services with an explicit Type.
+// ok=false means the service uses the legacy systemd/port probe.
+func runTypedCheck(s ServiceInfo) (res checkResult, ok bool) {
+	switch s.Type {
+	case "":
+		return checkResult{}, false
+	case "http":
+		return checkHTTP(s.HTTP), true
+	case "ping":
+		return checkPing(s.Ping), true
+	case "file_check":
+		return checkFile(s.File), true
+	case "cert_file":
+		return checkCertFiles(s.Cert), true
+	case "systemd_timer":
+		return checkSystemdTimer(s), true
+	default:
+		return checkResult{Reason: fmt.Sprintf("unknown check type %q", s.Type)}, true
+	}
+}
+
+// serviceState maps probe flags to the State shown in the UI and log.
+func serviceState(active, degraded bool) string {
+	switch {
+	case !active:
+		return "down"
+	case degraded:
+		return "degraded"
+	default:
+		return "up"
+	}
+}
+
+// parseDurationDefault parses a config duration string, falling back to def
+// for empty, invalid or non-positive values.
+func parseDurationDefault(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
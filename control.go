@@ -2,11 +2,13 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -70,6 +72,41 @@ func stopService(s ServiceInfo) error {
 	return fmt.Errorf("unsupported stop")
 }
 
+// controlAllowed applies the per-service control flags to a start/stop
+// request; it returns an HTTP status and message when the action is refused.
+func controlAllowed(si ServiceInfo, kind string) (int, string) {
+	switch {
+	case !si.Controls:
+		return http.StatusForbidden, "controls disabled"
+	case kind == "start" && !si.ControlsRun:
+		return http.StatusForbidden, "start disabled"
+	case kind == "stop" && !si.ControlsShut:
+		return http.StatusForbidden, "stop disabled"
+	}
+	return 0, ""
+}
+
+// performAction runs start/stop and records it in metrics, the action log,
+// the open incident of the service, if any, and notifiers ("action").
+func performAction(si ServiceInfo, kind, user, ip string) error {
+	var err error
+	if kind == "start" {
+		err = startService(si)
+	} else {
+		err = stopService(si)
+	}
+	metricsCountAction(kind, err)
+	result := "ok"
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	now := time.Now()
+	_ = logAction(appCfg.LogFile, now, user, ip, &si, kind, result)
+	incidents.addAction(si.Name, IncidentAction{Time: now, User: user, Action: kind, Result: result})
+	notifyAction(si, kind, user, result, now)
+	return err
+}
+
 func runCmd(cmd *exec.Cmd) error {
 	out, err := cmd.CombinedOutput()
 	if err != nil {
@@ -78,11 +115,16 @@ func runCmd(cmd *exec.Cmd) error {
 	return nil
 }
 
+// serialises read-modify-write of the log (monitor loop + HTTP handlers)
+var logMu sync.Mutex
+
 // prepend log line, enforce max size, no streaming
 func logAction(logFile string, ts time.Time, user, ip string, s *ServiceInfo, action, result string) error {
 	if logFile == "" {
 		logFile = "log.csv"
 	}
+	logMu.Lock()
+	defer logMu.Unlock()
 	header := "timestamp,user,ip,action,name,service_name,systemd_name,port,result\n"
 	var existing []byte
 	if b, err := os.ReadFile(logFile); err == nil {
@@ -104,21 +146,20 @@ func logAction(logFile string, ts time.Time, user, ip string, s *ServiceInfo, ac
 		head = []byte(header)
 		body = existing
 	}
-	content := append(append(head, []byte(line)...), body...)
+	// fresh buffer: head and body share the backing array of existing
+	content := make([]byte, 0, len(head)+len(line)+len(body))
+	content = append(append(append(content, head...), line...), body...)
 	max := appCfg.LogMaxBytes
 	if max > 0 && len(content) > max {
-		// keep header then trim body
+		// keep header then trim body (new line first)
 		if len(head) >= max {
-			content = head[:max]
+			content = content[:max]
 		} else {
-			bodyMax := max - len(head)
-			if bodyMax < len(body) {
-				body = body[:bodyMax]
-			}
-			if j := strings.LastIndex(string(body), "\n"); j > 0 {
-				body = body[:j+1]
+			rest := content[len(head):max]
+			if j := strings.LastIndex(string(rest), "\n"); j > 0 {
+				rest = rest[:j+1]
 			}
-			content = append(head, body...)
+			content = content[:len(head)+len(rest)]
 		}
 	}
 	TMP := logFile + ".tmp"
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -11,8 +12,12 @@ type EnvConfig struct {
 	ExportName     string
 	ImportPath     string
 	ImportName     string
+	ExportFormat   string // "v2" (default) or "legacy"
 	StatusInterval time.Duration
+	StaleFactor    float64 // imports older than StaleFactor*interval are stale
 	DialTimeout    time.Duration
+	CheckWorkers   int           // checks running at once per cycle
+	CheckDeadline  time.Duration // a check still running after this is down
 }
 
 // LoadEnv reads environment variables and applies defaults.
@@ -22,8 +27,12 @@ type EnvConfig struct {
 //	EXPORT_NAME      -> "status.json"
 //	IMPORT_PATH      -> EXPORT_PATH
 //	IMPORT_NAME      -> EXPORT_NAME
+//	EXPORT_FORMAT    -> "v2" ("legacy" writes the old bare array)
 //	STATUS_INTERVAL  -> "5s" (time.Duration)
+//	STALE_FACTOR     -> 3 (multiple of the exporter interval)
 //	PORT_DIAL_TIMEOUT-> "200ms" (time.Duration)
+//	CHECK_WORKERS    -> 8
+//	CHECK_DEADLINE   -> "30s" (time.Duration)
 func LoadEnv() EnvConfig {
 	exportPath := os.Getenv("EXPORT_PATH")
 	if exportPath == "" {
@@ -45,6 +54,11 @@ func LoadEnv() EnvConfig {
 		importName = exportName
 	}
 
+	exportFormat := os.Getenv("EXPORT_FORMAT")
+	if exportFormat != "legacy" {
+		exportFormat = "v2"
+	}
+
 	intervalStr := os.Getenv("STATUS_INTERVAL")
 	statusInterval := 5 * time.Second
 	if intervalStr != "" {
@@ -53,6 +67,11 @@ func LoadEnv() EnvConfig {
 		}
 	}
 
+	staleFactor := 3.0
+	if v, err := strconv.ParseFloat(os.Getenv("STALE_FACTOR"), 64); err == nil && v >= 1 {
+		staleFactor = v
+	}
+
 	dialStr := os.Getenv("PORT_DIAL_TIMEOUT")
 	dialTimeout := 200 * time.Millisecond
 	if dialStr != "" {
@@ -61,12 +80,26 @@ func LoadEnv() EnvConfig {
 		}
 	}
 
+	checkWorkers := 8
+	if v, err := strconv.Atoi(os.Getenv("CHECK_WORKERS")); err == nil && v > 0 {
+		checkWorkers = v
+	}
+
+	checkDeadline := 30 * time.Second
+	if dur, err := time.ParseDuration(os.Getenv("CHECK_DEADLINE")); err == nil && dur > 0 {
+		checkDeadline = dur
+	}
+
 	return EnvConfig{
 		ExportPath:     exportPath,
 		ExportName:     exportName,
 		ImportPath:     importPath,
 		ImportName:     importName,
+		ExportFormat:   exportFormat,
 		StatusInterval: statusInterval,
+		StaleFactor:    staleFactor,
 		DialTimeout:    dialTimeout,
+		CheckWorkers:   checkWorkers,
+		CheckDeadline:  checkDeadline,
 	}
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Atom 1.0 document (only the elements we emit)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string    `xml:"id"`
+	Title    string    `xml:"title"`
+	Updated  string    `xml:"updated"`
+	Link     atomLink  `xml:"link"`
+	Category []atomCat `xml:"category"`
+	Summary  string    `xml:"summary"`
+	updated  time.Time // sort key
+}
+
+type atomCat struct {
+	Term string `xml:"term,attr"`
+}
+
+// statusRow is one "status" line of the action log.
+type statusRow struct {
+	At      time.Time
+	Service string
+	State   string
+}
+
+// readStatusRows returns status transitions from the CSV log, oldest first.
+func readStatusRows(path string) []statusRow {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	var rows []statusRow
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		// timestamp,user,ip,action,name,service_name,systemd_name,port,result
+		if err != nil || len(rec) < 9 || rec[3] != "status" {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, rec[0])
+		if err != nil {
+			continue
+		}
+		rows = append(rows, statusRow{At: ts, Service: rec[4], State: rec[8]})
+	}
+	// the log is newest first
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].At.Before(rows[j].At) })
+	return rows
+}
+
+// feedFilter selects which services appear in a feed.
+type feedFilter struct {
+	service string // name or slug
+	tag     string
+	anon    bool
+	infos   map[string]ServiceInfo
+}
+
+func (f feedFilter) allows(name string) bool {
+	si, known := f.infos[name]
+	if f.anon && known && si.Private {
+		return false
+	}
+	if f.service != "" && !strings.EqualFold(name, f.service) && serviceSlug(name) != f.service {
+		return false
+	}
+	if f.tag != "" && !hasAnyTag(si.Tags, []string{f.tag}) {
+		return false
+	}
+	return true
+}
+
+// buildFeedEntries merges status transitions and incidents, newest first.
+func buildFeedEntries(rows []statusRow, incs []Incident, f feedFilter, base string, limit int) []atomEntry {
+	var entries []atomEntry
+	since := map[string]time.Time{} // service -> previous transition
+	for _, row := range rows {
+		prev, hadPrev := since[row.Service]
+		since[row.Service] = row.At
+		if !f.allows(row.Service) {
+			continue
+		}
+		summary := fmt.Sprintf("%s changed to %s at %s.", row.Service, row.State, row.At.Local().Format("2006-01-02 15:04:05 MST"))
+		if hadPrev {
+			summary += " Previous state lasted " + formatDurationSec(int64(row.At.Sub(prev).Seconds())) + "."
+		}
+		entries = append(entries, atomEntry{
+			ID:       fmt.Sprintf("urn:spm:status:%s:%d", serviceSlug(row.Service), row.At.Unix()),
+			Title:    fmt.Sprintf("%s is %s", row.Service, row.State),
+			Updated:  row.At.UTC().Format(time.RFC3339),
+			Link:     atomLink{Href: base + "/"},
+			Category: []atomCat{{Term: "status"}, {Term: row.State}},
+			Summary:  summary,
+			updated:  row.At,
+		})
+	}
+	for _, in := range incs {
+		if !f.allows(in.Service) {
+			continue
+		}
+		title := fmt.Sprintf("Incident: %s down", in.Service)
+		updated := in.Start
+		summary := fmt.Sprintf("%s went down at %s", in.Service, in.Start.Local().Format("2006-01-02 15:04:05 MST"))
+		if in.End != nil {
+			title = fmt.Sprintf("Resolved: %s down for %s", in.Service, formatDurationSec(in.Duration))
+			updated = *in.End
+			summary += fmt.Sprintf(", recovered at %s after %s", in.End.Local().Format("2006-01-02 15:04:05 MST"), formatDurationSec(in.Duration))
+		} else {
+			summary += ", still ongoing (" + formatDurationSec(in.Duration) + ")"
+		}
+		summary += "."
+		if in.Reason != "" {
+			summary += " Reason: " + in.Reason
+		}
+		entries = append(entries, atomEntry{
+			ID:       "urn:spm:incident:" + in.ID,
+			Title:    title,
+			Updated:  updated.UTC().Format(time.RFC3339),
+			Link:     atomLink{Href: base + "/api/incidents?service=" + url.QueryEscape(in.Service)},
+			Category: []atomCat{{Term: "incident"}},
+			Summary:  summary,
+			updated:  updated,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].updated.After(entries[j].updated) })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// requestBaseURL derives the public origin, honouring a reverse proxy.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedHandler serves /feed.atom[?service=&tag=&limit=]. Private services
+// are left out unless the reader is logged in.
+func feedHandler(infos []ServiceInfo) http.HandlerFunc {
+	byName := make(map[string]ServiceInfo, len(infos))
+	for _, si := range infos {
+		byName[si.Name] = si
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		f := feedFilter{service: q.Get("service"), tag: q.Get("tag"), anon: authUser(r) == "", infos: byName}
+		limit := 50
+		if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+		logFile := appCfg.LogFile
+		if logFile == "" {
+			logFile = "log.csv"
+		}
+		base := requestBaseURL(r)
+		entries := buildFeedEntries(readStatusRows(logFile), incidents.list("", time.Time{}, time.Time{}, 0), f, base, limit)
+
+		title := "SP Monitor"
+		switch {
+		case f.service != "":
+			title += " — " + f.service
+		case f.tag != "":
+			title += " — #" + f.tag
+		}
+		updated := time.Now()
+		if len(entries) > 0 {
+			updated = entries[0].updated
+		}
+		feed := atomFeed{
+			ID:      base + r.URL.RequestURI(),
+			Title:   title,
+			Updated: updated.UTC().Format(time.RFC3339),
+			Link:    []atomLink{{Href: base + r.URL.RequestURI(), Rel: "self"}, {Href: base + "/"}},
+			Author:  atomAuthor{Name: "SP Monitor"},
+			Entries: entries,
+		}
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		enc.Indent("", "  ")
+		if err := enc.Encode(feed); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// History layout: one append-only JSON-lines file per service in history_dir.
+// Check results are aggregated into minute buckets; buckets older than
+// historyFineWindow are downsampled to hourly ones and anything older than
+// the retention is dropped when the file is compacted.
+const (
+	historyFineSpan    = 60
+	historyCoarseSpan  = 3600
+	historyFineWindow  = 48 * time.Hour
+	historyCompactEach = time.Hour
+)
+
+// uptimeWindows are exposed in status.json and on the cards.
+var uptimeWindows = []struct {
+	Label string
+	Dur   time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"90d", 90 * 24 * time.Hour},
+}
+
+// historyBucket aggregates the check results of one service over Span seconds.
+type historyBucket struct {
+	Start    int64   `json:"t"`
+	Span     int64   `json:"s"`
+	Up       int     `json:"up,omitempty"`
+	Degraded int     `json:"dg,omitempty"`
+	Down     int     `json:"dn,omitempty"`
+	Maint    int     `json:"mt,omitempty"` // samples inside a maintenance window
+	MaintUp  int     `json:"mu,omitempty"` // ...of which the service was actually up
+	LatSum   float64 `json:"ls,omitempty"` // ms
+	LatN     int     `json:"ln,omitempty"`
+	Reason   string  `json:"r,omitempty"` // last failure reason in the bucket
+}
+
+// total counts samples outside maintenance.
+func (b historyBucket) total() int { return b.Up + b.Degraded + b.Down }
+
+func (b *historyBucket) merge(o historyBucket) {
+	b.Up += o.Up
+	b.Degraded += o.Degraded
+	b.Down += o.Down
+	b.Maint += o.Maint
+	b.MaintUp += o.MaintUp
+	b.LatSum += o.LatSum
+	b.LatN += o.LatN
+	if o.Reason != "" {
+		b.Reason = o.Reason
+	}
+}
+
+type historySeries struct {
+	buckets []historyBucket // closed, ordered by Start
+	cur     historyBucket   // open minute bucket
+}
+
+type historyStore struct {
+	sync.RWMutex
+	dir         string
+	readOnly    bool // report CLI: never write files
+	retention   time.Duration
+	series      map[string]*historySeries
+	lastCompact time.Time
+}
+
+var statusHistory *historyStore
+
+// historyKey maps a service name to its file name: the slug plus a hash
+// of the exact name, so "API-1" and "api 1" keep separate histories.
+func historyKey(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%s-%08x", serviceSlug(name), h.Sum32())
+}
+
+// serviceSlug is the readable form of a name used in URLs ("my-app" for
+// "My App"). Different names can share a slug.
+func serviceSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r > 127 {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteByte('-')
+		}
+	}
+	k := strings.Trim(b.String(), "-")
+	if k == "" {
+		k = "service"
+	}
+	return k
+}
+
+// openHistory loads existing series from dir and compacts them.
+// A readOnly store is used by the report command while the server may be
+// writing the same files. names are the configured services, used to
+// adopt files written before keys carried a hash.
+func openHistory(dir string, retention time.Duration, readOnly bool, names []string) (*historyStore, error) {
+	if !readOnly {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	h := &historyStore{dir: dir, readOnly: readOnly, retention: retention, series: map[string]*historySeries{}}
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		key := strings.TrimSuffix(filepath.Base(f), ".jsonl")
+		buckets, err := readHistoryFile(f)
+		if err != nil {
+			log.Printf("history: skip %s: %v", f, err)
+			continue
+		}
+		h.series[key] = &historySeries{buckets: buckets}
+	}
+	h.adoptLegacy(names)
+	h.compact(time.Now())
+	return h, nil
+}
+
+// adoptLegacy renames slug-only history files to the service's key. A
+// slug shared by several services holds their mixed samples and is left
+// alone.
+func (h *historyStore) adoptLegacy(names []string) {
+	bySlug := map[string][]string{}
+	for _, name := range names {
+		bySlug[serviceSlug(name)] = append(bySlug[serviceSlug(name)], name)
+	}
+	for slug, owners := range bySlug {
+		ser := h.series[slug]
+		if ser == nil {
+			continue
+		}
+		if len(owners) > 1 {
+			log.Printf("history: %s.jsonl is shared by %s, not migrated", slug, strings.Join(owners, ", "))
+			continue
+		}
+		key := historyKey(owners[0])
+		if h.series[key] != nil {
+			continue
+		}
+		h.series[key] = ser
+		delete(h.series, slug)
+		if h.readOnly {
+			continue
+		}
+		if err := os.Rename(filepath.Join(h.dir, slug+".jsonl"), filepath.Join(h.dir, key+".jsonl")); err != nil {
+			log.Printf("history: migrate %s: %v", slug, err)
+		}
+	}
+}
+
+func readHistoryFile(path string) ([]historyBucket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var res []historyBucket
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var b historyBucket
+		if err := json.Unmarshal(sc.Bytes(), &b); err != nil || b.Span <= 0 {
+			continue // tolerate a torn last line
+		}
+		res = append(res, b)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res, sc.Err()
+}
+
+// record adds the results of one check cycle.
+func (h *historyStore) record(services []Service, now time.Time) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	minute := now.Unix() - now.Unix()%historyFineSpan
+	for _, s := range services {
+		key := historyKey(s.Name)
+		ser := h.series[key]
+		if ser == nil {
+			ser = &historySeries{}
+			h.series[key] = ser
+		}
+		if ser.cur.Span != 0 && ser.cur.Start != minute {
+			h.closeBucket(key, ser)
+		}
+		if ser.cur.Span == 0 {
+			ser.cur = historyBucket{Start: minute, Span: historyFineSpan}
+		}
+		switch s.State {
+		case "up":
+			ser.cur.Up++
+		case "degraded":
+			ser.cur.Degraded++
+		case "maintenance":
+			ser.cur.Maint++
+			if s.Active {
+				ser.cur.MaintUp++
+			}
+		default:
+			ser.cur.Down++
+		}
+		if (s.State == "down" || s.State == "degraded") && s.Reason != "" {
+			ser.cur.Reason = s.Reason
+		}
+		if s.LatencyMs > 0 {
+			ser.cur.LatSum += s.LatencyMs
+			ser.cur.LatN++
+		}
+	}
+	if now.Sub(h.lastCompact) >= historyCompactEach {
+		h.compact(now)
+	}
+}
+
+// closeBucket appends the open bucket to memory and disk. Caller holds the lock.
+func (h *historyStore) closeBucket(key string, ser *historySeries) {
+	b := ser.cur
+	ser.cur = historyBucket{}
+	ser.buckets = append(ser.buckets, b)
+	line, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(h.dir, key+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("history append error: %v", err)
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(line, '\n'))
+}
+
+// compact downsamples old minute buckets to hours, applies retention and
+// rewrites the files. Caller holds the lock (or owns h exclusively).
+func (h *historyStore) compact(now time.Time) {
+	h.lastCompact = now
+	cutoff := now.Add(-h.retention).Unix()
+	fineFrom := now.Add(-historyFineWindow).Unix()
+	for key, ser := range h.series {
+		var out []historyBucket
+		for _, b := range ser.buckets {
+			if b.Start+b.Span <= cutoff {
+				continue
+			}
+			if b.Start < fineFrom && b.Span < historyCoarseSpan {
+				hour := b.Start - b.Start%historyCoarseSpan
+				if n := len(out); n > 0 && out[n-1].Start == hour && out[n-1].Span == historyCoarseSpan {
+					out[n-1].merge(b)
+					continue
+				}
+				nb := historyBucket{Start: hour, Span: historyCoarseSpan}
+				nb.merge(b)
+				out = append(out, nb)
+				continue
+			}
+			out = append(out, b)
+		}
+		ser.buckets = out
+		if h.readOnly {
+			continue
+		}
+		var buf []byte
+		for _, b := range out {
+			line, _ := json.Marshal(b)
+			buf = append(append(buf, line...), '\n')
+		}
+		if err := osWriteAtomic(filepath.Join(h.dir, key+".jsonl"), buf); err != nil {
+			log.Printf("history compact error (%s): %v", key, err)
+		}
+	}
+}
+
+// counts sums the samples of a service in buckets overlapping [from, to).
+// Maintenance samples are excluded unless includeMaint is set, in which
+// case they count by the actual probe result.
+func (h *historyStore) counts(name string, from, to time.Time, includeMaint bool) (avail, total int) {
+	if h == nil {
+		return 0, 0
+	}
+	h.RLock()
+	defer h.RUnlock()
+	ser := h.series[historyKey(name)]
+	if ser == nil {
+		return 0, 0
+	}
+	f, t := from.Unix(), to.Unix()
+	add := func(b historyBucket) {
+		if b.Span == 0 || b.Start+b.Span <= f || b.Start >= t {
+			return
+		}
+		avail += b.Up + b.Degraded
+		total += b.total()
+		if includeMaint {
+			avail += b.MaintUp
+			total += b.Maint
+		}
+	}
+	for _, b := range ser.buckets {
+		add(b)
+	}
+	add(ser.cur)
+	return avail, total
+}
+
+// uptime returns the availability percentage of a service since `since`
+// (degraded counts as available); ok=false when no samples exist.
+func (h *historyStore) uptime(name string, since time.Time) (pct float64, ok bool) {
+	avail, total := h.counts(name, since, time.Now().Add(time.Minute), false)
+	if total == 0 {
+		return 0, false
+	}
+	return float64(avail) * 100 / float64(total), true
+}
+
+// decorate fills Service.Uptime for the standard windows.
+func (h *historyStore) decorate(services []Service, now time.Time) {
+	if h == nil {
+		return
+	}
+	for i := range services {
+		for _, w := range uptimeWindows {
+			if pct, ok := h.uptime(services[i].Name, now.Add(-w.Dur)); ok {
+				if services[i].Uptime == nil {
+					services[i].Uptime = map[string]float64{}
+				}
+				services[i].Uptime[w.Label] = pct
+			}
+		}
+	}
+}
+
+// Dashboard timeline: one slot per hour over the fine-grained window.
+const (
+	timelineStep    = time.Hour
+	timelineSlots   = int(historyFineWindow / timelineStep)
+	timelinePartial = 99.0 // below this a slot with outages is "down", above "partial"
+)
+
+// timelineSlot is one bar of the per-card uptime strip.
+type timelineSlot struct {
+	Start       time.Time
+	State       string // none, up, degraded, partial, down, maintenance
+	DownMinutes float64
+	Uptime      float64
+	Title       string
+}
+
+// timeline aggregates the last timelineSlots hours of a service, oldest first.
+func (h *historyStore) timeline(name string, now time.Time) []timelineSlot {
+	if h == nil {
+		return nil
+	}
+	h.RLock()
+	defer h.RUnlock()
+	ser := h.series[historyKey(name)]
+	if ser == nil {
+		return nil
+	}
+	end := now.Truncate(timelineStep).Add(timelineStep)
+	first := end.Add(-time.Duration(timelineSlots) * timelineStep)
+	agg := make([]historyBucket, timelineSlots)
+	downMin := make([]float64, timelineSlots)
+	add := func(b historyBucket) {
+		if b.Span == 0 || b.total()+b.Maint == 0 {
+			return
+		}
+		i := int(time.Unix(b.Start, 0).Sub(first) / timelineStep)
+		if i < 0 || i >= timelineSlots {
+			return
+		}
+		agg[i].merge(b)
+		downMin[i] += float64(b.Down) / float64(b.total()+b.Maint) * float64(b.Span) / 60
+	}
+	for _, b := range ser.buckets {
+		add(b)
+	}
+	add(ser.cur)
+
+	res := make([]timelineSlot, timelineSlots)
+	for i, b := range agg {
+		start := first.Add(time.Duration(i) * timelineStep)
+		sl := timelineSlot{Start: start, State: "none", DownMinutes: downMin[i]}
+		label := start.Format("02 Jan 15:04") + "–" + start.Add(timelineStep).Format("15:04")
+		if b.total() == 0 {
+			sl.Title = label + " · no data"
+			if b.Maint > 0 {
+				sl.State = "maintenance"
+				sl.Title = label + " · maintenance"
+			}
+			res[i] = sl
+			continue
+		}
+		sl.Uptime = float64(b.Up+b.Degraded) * 100 / float64(b.total())
+		switch {
+		case b.Down > 0 && sl.Uptime < timelinePartial:
+			sl.State = "down"
+		case b.Down > 0:
+			sl.State = "partial"
+		case b.Degraded > 0:
+			sl.State = "degraded"
+		default:
+			sl.State = "up"
+		}
+		sl.Title = fmt.Sprintf("%s · %s", label, formatUptime(sl.Uptime))
+		if b.Maint > 0 {
+			sl.Title += " · partly maintenance"
+		}
+		if b.Down > 0 {
+			sl.Title += fmt.Sprintf(" · %.0f min outage", math.Ceil(sl.DownMinutes))
+			if b.Reason != "" {
+				sl.Title += " · " + b.Reason
+			}
+		}
+		res[i] = sl
+	}
+	return res
+}
+
+// uptimeList orders a Service.Uptime map by window for the template.
+func uptimeList(m map[string]float64) []struct{ Label, Text string } {
+	var res []struct{ Label, Text string }
+	for _, w := range uptimeWindows {
+		if p, ok := m[w.Label]; ok {
+			res = append(res, struct{ Label, Text string }{w.Label, formatUptime(p)})
+		}
+	}
+	return res
+}
+
+// formatUptime renders a percentage for the UI (100%, 99.95%, 87.3%).
+func formatUptime(p float64) string {
+	switch {
+	case p >= 100:
+		return "100%"
+	case p >= 99:
+		return fmt.Sprintf("%.2f%%", math.Floor(p*100)/100)
+	default:
+		return fmt.Sprintf("%.1f%%", math.Floor(p*10)/10)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IncidentAction is a start/stop attempt made while an incident was open.
+type IncidentAction struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user,omitempty"`
+	Action string    `json:"action"`
+	Result string    `json:"result"`
+}
+
+// Incident spans from a service going down until it recovers.
+type Incident struct {
+	ID       string           `json:"id"`
+	Service  string           `json:"service"`
+	Start    time.Time        `json:"start"`
+	End      *time.Time       `json:"end,omitempty"`
+	Duration int64            `json:"duration_seconds"`
+	Reason   string           `json:"reason,omitempty"` // first failure reason
+	Actions  []IncidentAction `json:"actions,omitempty"`
+	AckedBy  string           `json:"acked_by,omitempty"`
+	AckedAt  *time.Time       `json:"acked_at,omitempty"`
+	// paging progress, kept so a restart does not page again
+	Reminders   int        `json:"reminders,omitempty"`
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"`
+}
+
+func (in *Incident) open() bool { return in.End == nil }
+
+type incidentStore struct {
+	sync.RWMutex
+	path      string
+	retention time.Duration
+	items     []Incident // ordered by Start
+}
+
+var incidents *incidentStore
+
+func openIncidents(path string, retention time.Duration) *incidentStore {
+	st := &incidentStore{path: path, retention: retention}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &st.items); err != nil {
+			log.Printf("incidents file parse error, starting empty: %v", err)
+			st.items = nil
+		}
+	}
+	return st
+}
+
+// saveLocked prunes closed incidents past retention and writes the file.
+func (st *incidentStore) saveLocked(now time.Time) {
+	if st.retention > 0 {
+		cutoff := now.Add(-st.retention)
+		kept := st.items[:0]
+		for _, in := range st.items {
+			if in.open() || in.End.After(cutoff) {
+				kept = append(kept, in)
+			}
+		}
+		st.items = kept
+	}
+	data, err := json.MarshalIndent(st.items, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := osWriteAtomic(st.path, data); err != nil {
+		log.Printf("incidents write error: %v", err)
+	}
+}
+
+func (st *incidentStore) openIndexLocked(name string) int {
+	for i := len(st.items) - 1; i >= 0; i-- {
+		if st.items[i].open() && st.items[i].Service == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// observe opens an incident when s is down and none is open, and closes the
+// open one once s is up or degraded again. It returns a copy of the
+// incident it opened or closed, nil when nothing changed.
+func (st *incidentStore) observe(s Service, now time.Time) *Incident {
+	if st == nil {
+		return nil
+	}
+	st.Lock()
+	defer st.Unlock()
+	idx := st.openIndexLocked(s.Name)
+	switch {
+	case s.State == "down" && idx < 0:
+		st.items = append(st.items, Incident{
+			ID:      fmt.Sprintf("%d-%s", now.Unix(), historyKey(s.Name)),
+			Service: s.Name,
+			Start:   now,
+			Reason:  s.Reason,
+		})
+		idx = len(st.items) - 1
+	case s.State != "down" && idx >= 0:
+		in := &st.items[idx]
+		end := now
+		in.End = &end
+		in.Duration = int64(end.Sub(in.Start).Seconds())
+	default:
+		return nil
+	}
+	st.saveLocked(now)
+	in := st.items[idx]
+	in.Actions = nil
+	return &in
+}
+
+// addAction attaches a start/stop attempt to the open incident of a service.
+func (st *incidentStore) addAction(name string, a IncidentAction) {
+	if st == nil {
+		return
+	}
+	st.Lock()
+	defer st.Unlock()
+	idx := st.openIndexLocked(name)
+	if idx < 0 {
+		return
+	}
+	st.items[idx].Actions = append(st.items[idx].Actions, a)
+	st.saveLocked(a.Time)
+}
+
+// active returns copies of the open incidents, oldest first.
+func (st *incidentStore) active() []Incident {
+	if st == nil {
+		return nil
+	}
+	st.RLock()
+	defer st.RUnlock()
+	var res []Incident
+	for _, in := range st.items {
+		if in.open() {
+			in.Actions = nil
+			res = append(res, in)
+		}
+	}
+	return res
+}
+
+// acknowledge marks an incident as handled by user. Acknowledging twice
+// keeps the first acknowledgement and reports ok=false.
+func (st *incidentStore) acknowledge(id, user string, now time.Time) (in Incident, ok bool, err error) {
+	if st == nil {
+		return in, false, fmt.Errorf("incidents disabled")
+	}
+	st.Lock()
+	defer st.Unlock()
+	for i := range st.items {
+		if st.items[i].ID != id {
+			continue
+		}
+		it := &st.items[i]
+		if it.AckedAt == nil {
+			at := now
+			it.AckedAt = &at
+			it.AckedBy = user
+			it.Actions = append(it.Actions, IncidentAction{Time: now, User: user, Action: "ack", Result: "ok"})
+			st.saveLocked(now)
+			ok = true
+		}
+		return *it, ok, nil
+	}
+	return in, false, fmt.Errorf("incident not found")
+}
+
+// recordAlert stores the reminders sent for an open incident and when it
+// was escalated.
+func (st *incidentStore) recordAlert(id string, reminders int, escalatedAt *time.Time, now time.Time) {
+	if st == nil {
+		return
+	}
+	st.Lock()
+	defer st.Unlock()
+	for i := range st.items {
+		if st.items[i].ID == id {
+			st.items[i].Reminders = reminders
+			st.items[i].EscalatedAt = escalatedAt
+			st.saveLocked(now)
+			return
+		}
+	}
+}
+
+// ackIncident acknowledges an incident, writes it to the action log and
+// sends an "ack" event; alert reminders for it stop. via is recorded in
+// the ip column (client IP, "telegram", ...).
+func ackIncident(id, user, via string) (Incident, bool, error) {
+	now := time.Now()
+	in, ok, err := incidents.acknowledge(id, user, now)
+	if err != nil || !ok {
+		return in, ok, err
+	}
+	_ = logAction(appCfg.LogFile, now, user, via, &ServiceInfo{Name: in.Service}, "ack", id)
+	notifyAck(in, user, now)
+	return in, true, nil
+}
+
+// list returns incidents overlapping [from, to] (zero = unbounded), newest first.
+func (st *incidentStore) list(service string, from, to time.Time, limit int) []Incident {
+	if st == nil {
+		return nil
+	}
+	st.RLock()
+	defer st.RUnlock()
+	now := time.Now()
+	res := []Incident{}
+	for i := len(st.items) - 1; i >= 0; i-- {
+		in := st.items[i]
+		if service != "" && !strings.EqualFold(in.Service, service) {
+			continue
+		}
+		end := now
+		if in.End != nil {
+			end = *in.End
+		}
+		if (!from.IsZero() && end.Before(from)) || (!to.IsZero() && in.Start.After(to)) {
+			continue
+		}
+		if in.open() {
+			in.Duration = int64(now.Sub(in.Start).Seconds())
+		}
+		in.Actions = append([]IncidentAction(nil), in.Actions...)
+		res = append(res, in)
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+	}
+	return res
+}
+
+// parseTimeParam accepts RFC3339 or a plain date (YYYY-MM-DD, local time).
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// incidentsHandler serves GET /api/incidents?service=&from=&to=&limit=.
+// Anonymous callers get neither private services nor who acknowledged or
+// acted on an incident.
+func incidentsHandler(infos []ServiceInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		from, err := parseTimeParam(q.Get("from"))
+		if err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad from"})
+			return
+		}
+		to, err := parseTimeParam(q.Get("to"))
+		if err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad to"})
+			return
+		}
+		limit := 100
+		if l := q.Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 1000 {
+				limit = v
+			}
+		}
+		list := incidents.list(q.Get("service"), from, to, limit)
+		if !privateVisible(r) {
+			public := list[:0]
+			for _, in := range list {
+				if isPrivateService(infos, in.Service) {
+					continue
+				}
+				in.AckedBy = ""
+				for j := range in.Actions {
+					in.Actions[j].User = ""
+				}
+				public = append(public, in)
+			}
+			list = public
+		}
+		respondJSON(w, map[string]any{"incidents": list})
+	}
+}
@@ -158,21 +158,76 @@ func main() {
 	commonPw = loadCommonPasswords(commonPwPath)
 	log.Printf("commonPw loaded: %d entries (admin=%v, 'admin admin'=%v)", len(commonPw), isCommonPassword("admin"), isCommonPassword("admin admin"))
 
+	// Persistent per-service history (uptime percentages)
+	historyDir := appCfg.HistoryDir
+	if historyDir == "" {
+		historyDir = filepath.Join("data", "history")
+	}
+	retentionDays := appCfg.HistoryRetentionDays
+	if retentionDays <= 0 {
+		retentionDays = 90
+	}
+	// `port-monitor report ...` only reads history/incidents and exits
+	reportCLI := len(os.Args) > 1 && os.Args[1] == "report"
+	names := make([]string, 0, len(servicesConfig.Services))
+	for _, si := range servicesConfig.Services {
+		names = append(names, si.Name)
+	}
+	if h, err := openHistory(resolvePath(historyDir), time.Duration(retentionDays)*24*time.Hour, reportCLI, names); err != nil {
+		log.Println("History store disabled:", err)
+	} else {
+		statusHistory = h
+	}
+
+	// Incidents derived from status transitions
+	incidentsFile := appCfg.IncidentsFile
+	if incidentsFile == "" {
+		incidentsFile = filepath.Join("data", "incidents.json")
+	}
+	incidents = openIncidents(resolvePath(incidentsFile), time.Duration(retentionDays)*24*time.Hour)
+
+	// Maintenance windows: services.json + API-created ones
+	maintenanceFile := appCfg.MaintenanceFile
+	if maintenanceFile == "" {
+		maintenanceFile = filepath.Join("data", "maintenance.json")
+	}
+	maintenance = openMaintenance(resolvePath(maintenanceFile), servicesConfig.Maintenance)
+
+	// Outbound notifications on status changes
+	deliveryLog := appCfg.DeliveryLogFile
+	if deliveryLog == "" {
+		deliveryLog = filepath.Join("data", "deliveries.jsonl")
+	}
+	if !reportCLI {
+		setupNotifiers(appCfg.Notifiers, servicesConfig.Services, resolvePath(deliveryLog))
+		setupRouting(appCfg.Routes, appCfg.Notifiers)
+		startAlerts(appCfg.AlertPolicies)
+	}
+
+	reportTemplate := filepath.Join(webDirAbs, "report.html")
+	if reportCLI {
+		os.Exit(runReportCommand(os.Args[2:], servicesConfig.Services, reportTemplate))
+	}
+
+	// Federated peers polled into the dashboard, agents pushing to it,
+	// or this instance pushing to a server as an agent
+	startPeers(appCfg.Peers)
+	registerAgents(appCfg.Agents)
+	if appCfg.Agent != nil {
+		startAgent(*appCfg.Agent, servicesConfig.Services, statusFileWrite)
+	}
+
 	// Background exporter with change detection (non-blocking startup)
 	go func() {
 		// initial snapshot + export
-		curr := getServicesStatus(servicesConfig.Services)
-		detectAndLogStatusChanges(lastStatus, curr)
-		if err := exportStatusFile(servicesConfig.Services, statusFileWrite); err != nil {
+		if err := runStatusCycle(servicesConfig.Services, statusFileWrite); err != nil {
 			log.Println("Initial status export error:", err)
 		}
 		// periodic refresh
 		ticker := time.NewTicker(statusExportInterval)
 		defer ticker.Stop()
 		for range ticker.C {
-			curr := getServicesStatus(servicesConfig.Services)
-			detectAndLogStatusChanges(lastStatus, curr)
-			if err := exportStatusFile(servicesConfig.Services, statusFileWrite); err != nil {
+			if err := runStatusCycle(servicesConfig.Services, statusFileWrite); err != nil {
 				log.Println("Status export error:", err)
 			}
 		}
@@ -250,6 +305,7 @@ func main() {
 		ServiceName string `json:"service_name,omitempty"`
 		SystemdName string `json:"systemd_name,omitempty"`
 		Port        int    `json:"port,omitempty"`
+		Agent       string `json:"agent,omitempty"` // relay to this agent
 	}
 
 	actionHandler := func(kind string) http.HandlerFunc {
@@ -268,6 +324,10 @@ func main() {
 				respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad json"})
 				return
 			}
+			if req.Agent != "" {
+				relayAgentAction(w, r, req.Agent, kind, req.Name, user)
+				return
+			}
 			// find target
 			var target *ServiceInfo
 			for i := range servicesConfig.Services {
@@ -285,50 +345,63 @@ func main() {
 				return
 			}
 			// permissions
-			if !target.Controls {
-				respondJSONCode(w, http.StatusForbidden, map[string]string{"error": "controls disabled"})
-				return
-			}
-			if kind == "start" && !target.ControlsRun {
-				respondJSONCode(w, http.StatusForbidden, map[string]string{"error": "start disabled"})
-				return
-			}
-			if kind == "stop" && !target.ControlsShut {
-				respondJSONCode(w, http.StatusForbidden, map[string]string{"error": "stop disabled"})
+			if code, msg := controlAllowed(*target, kind); code != 0 {
+				respondJSONCode(w, code, map[string]string{"error": msg})
 				return
 			}
 
-			var err error
-			if kind == "start" {
-				err = startService(*target)
-			} else {
-				err = stopService(*target)
-			}
-			result := "ok"
-			if err != nil {
-				result = "error: " + err.Error()
+			if err := performAction(*target, kind, user, clientIP(r)); err != nil {
 				respondJSONCode(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": err.Error()})
-			} else {
-				respondJSON(w, map[string]any{"ok": true})
-				_ = exportStatusFile(servicesConfig.Services, statusFileWrite)
+				return
 			}
-			_ = logAction(appCfg.LogFile, time.Now(), user, clientIP(r), target, kind, result)
+			respondJSON(w, map[string]any{"ok": true})
+			_ = refreshStatusFile(servicesConfig.Services, statusFileWrite)
 		}
 	}
 
+	http.HandleFunc("/api/incidents", incidentsHandler(servicesConfig.Services))
+	http.HandleFunc("/api/maintenance", handleMaintenance)
+	http.HandleFunc("/api/notifications", handleNotifications)
+	http.HandleFunc("/api/alerts", handleAlerts)
+	http.HandleFunc("/api/alerts/", handleAlerts)
+	http.HandleFunc("/api/routes", handleRoutes)
+	http.HandleFunc("/api/routes/", handleRoutes)
+	http.HandleFunc("/api/peers", handlePeers)
+	http.HandleFunc("/api/agent/push", handleAgentPush)
+	http.HandleFunc("/api/agent/commands", handleAgentCommands)
+	http.HandleFunc("/api/agent/result", handleAgentResult)
+	http.Handle("/api/report", reportHandler(servicesConfig.Services, reportTemplate))
+
 	http.Handle("/api/service/start", actionHandler("start"))
 	http.Handle("/api/service/stop", actionHandler("stop"))
 
+	// Prometheus scrape endpoint
+	http.HandleFunc("/metrics", metricsHandler(servicesConfig.Services))
+
+	// Embeddable SVG badges
+	http.HandleFunc("/badge/", badgeHandler(servicesConfig.Services, statusFileRead))
+
+	// Atom feed of status changes and incidents
+	http.HandleFunc("/feed.atom", feedHandler(servicesConfig.Services))
+
 	// Expose exported status.json (optional consumption by clients)
-	http.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) { serveStatic(w, r, statusFileWrite) })
+	http.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		serveStatusJSON(w, r, statusFileWrite, servicesConfig.Services)
+	})
 
 	// Page
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		services, err := loadStatusFile(statusFileRead)
+		now := time.Now()
+		imp, err := readImportedStatus(statusFileRead, now)
+		services, banner := imp.Services, imp.staleBanner(now)
 		if err != nil {
 			services = defaultServicesFromInfo(servicesConfig.Services)
+			banner = "Status data is not available yet (the status file is missing or unreadable); showing configured services without live state."
+		}
+		if !privateVisible(r) {
+			services = publicServices(services, servicesConfig.Services)
 		}
-		renderHTML(w, services, templateFileAbs)
+		renderHTML(w, services, banner, templateFileAbs)
 	})
 
 	// Static (serve both root paths and legacy /web/*)
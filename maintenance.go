@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow marks services as "maintenance": transitions are logged
+// as such, no incidents/alerts are raised and SLA figures exclude the time.
+// Services and Tags select the targets; both empty means every service.
+// Either Start/End (one-off) or Recurring must be set.
+type MaintenanceWindow struct {
+	ID        string                 `json:"id,omitempty"`
+	Services  []string               `json:"services,omitempty"`
+	Tags      []string               `json:"tags,omitempty"`
+	Start     time.Time              `json:"start,omitzero"`
+	End       time.Time              `json:"end,omitzero"`
+	Recurring *MaintenanceRecurrence `json:"recurring,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+	CreatedBy string                 `json:"created_by,omitempty"`
+}
+
+// MaintenanceRecurrence repeats a window at Start (HH:MM in Timezone, default
+// local) for Duration on the given Days (mon..sun; empty = daily).
+type MaintenanceRecurrence struct {
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	Duration string   `json:"duration"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+func (m *MaintenanceWindow) validate() error {
+	if m.Recurring == nil {
+		if m.Start.IsZero() || !m.End.After(m.Start) {
+			return fmt.Errorf("one-off window needs start < end")
+		}
+		return nil
+	}
+	rc := m.Recurring
+	if _, err := time.Parse("15:04", rc.Start); err != nil {
+		return fmt.Errorf("recurring start must be HH:MM")
+	}
+	if d, err := time.ParseDuration(rc.Duration); err != nil || d <= 0 || d > 7*24*time.Hour {
+		return fmt.Errorf("recurring duration must be a positive duration")
+	}
+	if rc.Timezone != "" {
+		if _, err := time.LoadLocation(rc.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", rc.Timezone)
+		}
+	}
+	for _, d := range rc.Days {
+		if _, ok := parseWeekday(d); !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	return nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekday accepts a day name or its 3-letter prefix in any case.
+func parseWeekday(d string) (time.Weekday, bool) {
+	r := []rune(strings.ToLower(d))
+	wd, ok := weekdayNames[string(r[:min(3, len(r))])]
+	return wd, ok
+}
+
+// activeUntil reports whether the window covers now and when it ends.
+func (m *MaintenanceWindow) activeUntil(now time.Time) (time.Time, bool) {
+	if m.Recurring == nil {
+		return m.End, !now.Before(m.Start) && now.Before(m.End)
+	}
+	rc := m.Recurring
+	loc := time.Local
+	if rc.Timezone != "" {
+		if l, err := time.LoadLocation(rc.Timezone); err == nil {
+			loc = l
+		}
+	}
+	hm, err := time.Parse("15:04", rc.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	dur, err := time.ParseDuration(rc.Duration)
+	if err != nil {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	// a window that started on an earlier day may still be running
+	for back := 0; back <= int(dur/(24*time.Hour))+1; back++ {
+		day := local.AddDate(0, 0, -back)
+		start := time.Date(day.Year(), day.Month(), day.Day(), hm.Hour(), hm.Minute(), 0, 0, loc)
+		if !m.onDay(start.Weekday()) {
+			continue
+		}
+		if end := start.Add(dur); !now.Before(start) && now.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (m *MaintenanceWindow) onDay(wd time.Weekday) bool {
+	if len(m.Recurring.Days) == 0 {
+		return true
+	}
+	for _, d := range m.Recurring.Days {
+		if day, ok := parseWeekday(d); ok && day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MaintenanceWindow) matches(name string, tags []string) bool {
+	if len(m.Services) == 0 && len(m.Tags) == 0 {
+		return true
+	}
+	for _, s := range m.Services {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return hasAnyTag(tags, m.Tags)
+}
+
+// hasAnyTag reports whether any of want is in tags (case-insensitive).
+func hasAnyTag(tags, want []string) bool {
+	for _, w := range want {
+		for _, t := range tags {
+			if strings.EqualFold(t, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type maintenanceStore struct {
+	sync.RWMutex
+	path    string
+	config  []MaintenanceWindow // from services.json, read-only
+	created []MaintenanceWindow // via API, persisted to path
+}
+
+var maintenance *maintenanceStore
+
+func openMaintenance(path string, fromConfig []MaintenanceWindow) *maintenanceStore {
+	st := &maintenanceStore{path: path}
+	for i, m := range fromConfig {
+		if err := m.validate(); err != nil {
+			log.Printf("maintenance window %d ignored: %v", i, err)
+			continue
+		}
+		if m.ID == "" {
+			m.ID = fmt.Sprintf("cfg-%d", i+1)
+		}
+		st.config = append(st.config, m)
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &st.created); err != nil {
+			log.Printf("maintenance file parse error: %v", err)
+		}
+	}
+	return st
+}
+
+// active returns the window covering a service now, if any.
+func (st *maintenanceStore) active(name string, tags []string, now time.Time) (*MaintenanceWindow, time.Time) {
+	if st == nil {
+		return nil, time.Time{}
+	}
+	st.RLock()
+	defer st.RUnlock()
+	for _, list := range [][]MaintenanceWindow{st.config, st.created} {
+		for i := range list {
+			m := list[i]
+			if !m.matches(name, tags) {
+				continue
+			}
+			if end, ok := m.activeUntil(now); ok {
+				return &m, end
+			}
+		}
+	}
+	return nil, time.Time{}
+}
+
+// apply switches services inside a window to the "maintenance" state.
+// Active keeps the probe result so controls and history stay accurate.
+func (st *maintenanceStore) apply(services []Service, now time.Time) {
+	for i := range services {
+		m, end := st.active(services[i].Name, services[i].Tags, now)
+		if m == nil {
+			continue
+		}
+		services[i].State = "maintenance"
+		services[i].Maintenance = "until " + end.Local().Format("02 Jan 15:04")
+		if m.Reason != "" {
+			services[i].Maintenance += " · " + m.Reason
+		}
+	}
+}
+
+func (st *maintenanceStore) list() []MaintenanceWindow {
+	st.RLock()
+	defer st.RUnlock()
+	res := append([]MaintenanceWindow{}, st.config...)
+	return append(res, st.created...)
+}
+
+// saveLocked drops finished one-off windows and writes the API-created list.
+func (st *maintenanceStore) saveLocked(now time.Time) error {
+	kept := st.created[:0]
+	for _, m := range st.created {
+		if m.Recurring != nil || m.End.After(now) {
+			kept = append(kept, m)
+		}
+	}
+	st.created = kept
+	data, err := json.MarshalIndent(st.created, "", "  ")
+	if err != nil {
+		return err
+	}
+	return osWriteAtomic(st.path, data)
+}
+
+func (st *maintenanceStore) add(m MaintenanceWindow) error {
+	st.Lock()
+	defer st.Unlock()
+	st.created = append(st.created, m)
+	return st.saveLocked(time.Now())
+}
+
+func (st *maintenanceStore) remove(id string) (bool, error) {
+	st.Lock()
+	defer st.Unlock()
+	for i, m := range st.created {
+		if m.ID == id {
+			st.created = append(st.created[:i], st.created[i+1:]...)
+			return true, st.saveLocked(time.Now())
+		}
+	}
+	return false, nil
+}
+
+// handleMaintenance serves /api/maintenance:
+// GET lists windows (created_by only for logged-in users), POST creates one,
+// DELETE ?id= removes an API-created one.
+// Changes require login and are written to the action log.
+func handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		list := maintenance.list()
+		if authUser(r) == "" {
+			for i := range list {
+				list[i].CreatedBy = ""
+			}
+		}
+		respondJSON(w, map[string]any{"windows": list})
+		return
+	}
+	user := authUser(r)
+	if user == "" {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var m MaintenanceWindow
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "bad json"})
+			return
+		}
+		if err := m.validate(); err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		m.ID = newToken()[:8]
+		m.CreatedBy = user
+		if err := maintenance.add(m); err != nil {
+			respondJSONCode(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		_ = logAction(appCfg.LogFile, time.Now(), user, clientIP(r), &ServiceInfo{Name: maintenanceLabel(m)}, "maintenance_add", m.ID)
+		respondJSON(w, map[string]any{"ok": true, "window": m})
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		ok, err := maintenance.remove(id)
+		if err != nil {
+			respondJSONCode(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		if !ok {
+			respondJSONCode(w, http.StatusNotFound, map[string]string{"error": "window not found (config windows cannot be deleted)"})
+			return
+		}
+		_ = logAction(appCfg.LogFile, time.Now(), user, clientIP(r), &ServiceInfo{Name: "maintenance " + id}, "maintenance_delete", "ok")
+		respondJSON(w, map[string]any{"ok": true})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func maintenanceLabel(m MaintenanceWindow) string {
+	targets := append(append([]string{}, m.Services...), m.Tags...)
+	if len(targets) == 0 {
+		return "all services"
+	}
+	return strings.Join(targets, " ")
+}
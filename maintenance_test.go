@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceRecurringActiveUntil(t *testing.T) {
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-10-16 is a Friday; DST in New York ends 2026-11-01 and starts 2026-03-08
+	tests := []struct {
+		name   string
+		rc     MaintenanceRecurrence
+		now    string
+		active bool
+		end    string
+	}{
+		{"daily inside", MaintenanceRecurrence{Start: "02:00", Duration: "1h", Timezone: "UTC"}, "2026-10-16T02:30:00Z", true, "2026-10-16T03:00:00Z"},
+		{"daily before", MaintenanceRecurrence{Start: "02:00", Duration: "1h", Timezone: "UTC"}, "2026-10-16T01:59:59Z", false, ""},
+		{"end is exclusive", MaintenanceRecurrence{Start: "02:00", Duration: "1h", Timezone: "UTC"}, "2026-10-16T03:00:00Z", false, ""},
+		{"crosses midnight", MaintenanceRecurrence{Start: "23:00", Duration: "2h", Timezone: "UTC"}, "2026-10-17T00:30:00Z", true, "2026-10-17T01:00:00Z"},
+		{"crosses midnight from a listed day", MaintenanceRecurrence{Days: []string{"fri"}, Start: "23:00", Duration: "2h", Timezone: "UTC"}, "2026-10-17T00:30:00Z", true, "2026-10-17T01:00:00Z"},
+		{"day not listed", MaintenanceRecurrence{Days: []string{"fri"}, Start: "23:00", Duration: "2h", Timezone: "UTC"}, "2026-10-17T23:30:00Z", false, ""},
+		{"full day name", MaintenanceRecurrence{Days: []string{"Saturday"}, Start: "23:00", Duration: "2h", Timezone: "UTC"}, "2026-10-17T23:30:00Z", true, "2026-10-18T01:00:00Z"},
+		{"multi-day window", MaintenanceRecurrence{Days: []string{"mon"}, Start: "00:00", Duration: "48h", Timezone: "UTC"}, "2026-10-13T12:00:00Z", true, "2026-10-14T00:00:00Z"},
+		{"other timezone", MaintenanceRecurrence{Start: "09:00", Duration: "1h", Timezone: "Asia/Tokyo"}, "2026-10-17T00:30:00Z", true, "2026-10-17T01:00:00Z"},
+		{"other timezone before", MaintenanceRecurrence{Start: "09:00", Duration: "1h", Timezone: "Asia/Tokyo"}, "2026-10-16T23:30:00Z", false, ""},
+		// Saturday 08:30 in Tokyo is still Friday in UTC
+		{"days in the window's timezone", MaintenanceRecurrence{Days: []string{"sat"}, Start: "08:00", Duration: "2h", Timezone: "Asia/Tokyo"}, "2026-10-16T23:30:00Z", true, "2026-10-17T01:00:00Z"},
+		// 00:30 EDT + 2h ends at 01:30 EST, after the repeated hour
+		{"dst ends inside", MaintenanceRecurrence{Start: "00:30", Duration: "2h", Timezone: "America/New_York"}, "2026-11-01T06:15:00Z", true, "2026-11-01T06:30:00Z"},
+		{"dst ends after end", MaintenanceRecurrence{Start: "00:30", Duration: "2h", Timezone: "America/New_York"}, "2026-11-01T06:45:00Z", false, ""},
+		{"day after dst ends", MaintenanceRecurrence{Start: "00:30", Duration: "2h", Timezone: "America/New_York"}, "2026-11-02T05:40:00Z", true, "2026-11-02T07:30:00Z"},
+		// 01:00 EST + 3h ends at 05:00 EDT
+		{"dst starts inside", MaintenanceRecurrence{Start: "01:00", Duration: "3h", Timezone: "America/New_York"}, "2026-03-08T08:30:00Z", true, "2026-03-08T09:00:00Z"},
+		{"dst starts before start", MaintenanceRecurrence{Start: "01:00", Duration: "3h", Timezone: "America/New_York"}, "2026-03-08T05:59:00Z", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := tt.rc
+			m := &MaintenanceWindow{Recurring: &rc}
+			if err := m.validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			end, active := m.activeUntil(utc(tt.now))
+			if active != tt.active {
+				t.Fatalf("active = %v, want %v", active, tt.active)
+			}
+			if tt.active && !end.Equal(utc(tt.end)) {
+				t.Fatalf("end = %s, want %s", end.UTC().Format(time.RFC3339), tt.end)
+			}
+		})
+	}
+}
+
+func TestMaintenanceOneOff(t *testing.T) {
+	start := time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC)
+	m := &MaintenanceWindow{Start: start, End: start.Add(3 * time.Hour)}
+	if err := m.validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		now    time.Time
+		active bool
+	}{
+		{start.Add(-time.Second), false},
+		{start, true},
+		{start.Add(2 * time.Hour), true},
+		{start.Add(3 * time.Hour), false},
+	} {
+		if _, active := m.activeUntil(tc.now); active != tc.active {
+			t.Errorf("%s: active = %v, want %v", tc.now.Format(time.RFC3339), active, tc.active)
+		}
+	}
+	if err := (&MaintenanceWindow{Start: start, End: start}).validate(); err == nil {
+		t.Error("empty one-off window accepted")
+	}
+}
+
+func TestMaintenanceValidateDays(t *testing.T) {
+	for _, tc := range []struct {
+		day string
+		ok  bool
+	}{
+		{"mon", true},
+		{"MON", true},
+		{"Wednesday", true},
+		{"mo", false},
+		{"", false},
+		{"K", false}, // Kelvin sign lowercases to ASCII k
+		{"søn", false},
+	} {
+		m := &MaintenanceWindow{Recurring: &MaintenanceRecurrence{Days: []string{tc.day}, Start: "01:00", Duration: "1h"}}
+		if err := m.validate(); (err == nil) != tc.ok {
+			t.Errorf("day %q: err = %v, want ok=%v", tc.day, err, tc.ok)
+		}
+	}
+}
+
+func TestQuietHoursWindow(t *testing.T) {
+	m, err := QuietHours{Start: "22:00", End: "07:00", Days: []string{"fri"}, Timezone: "UTC"}.window()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fri := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		now    time.Time
+		active bool
+	}{
+		{fri.Add(21*time.Hour + 59*time.Minute), false},
+		{fri.Add(22 * time.Hour), true},
+		{fri.Add(30*time.Hour + 59*time.Minute), true}, // Saturday 06:59
+		{fri.Add(31 * time.Hour), false},               // Saturday 07:00
+		{fri.Add(46 * time.Hour), false},               // Saturday 22:00, not a listed day
+	} {
+		if _, active := m.activeUntil(tc.now); active != tc.active {
+			t.Errorf("%s: active = %v, want %v", tc.now.Format(time.RFC3339), active, tc.active)
+		}
+	}
+	if _, err := (QuietHours{Start: "22:00", End: "07:00", Days: []string{"K"}}).window(); err == nil {
+		t.Error("quiet hours with an unknown day accepted")
+	}
+}
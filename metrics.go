@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// monitor-internal counters and the last check snapshot for /metrics
+var metrics = struct {
+	sync.Mutex
+	latest      []Service
+	lastCycle   time.Duration
+	cycles      uint64
+	cycleSecSum float64
+	actions     map[[2]string]uint64 // action, result -> count
+}{actions: map[[2]string]uint64{}}
+
+func metricsObserveCycle(curr []Service, took time.Duration) {
+	snap := append([]Service(nil), curr...)
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.latest = snap
+	metrics.lastCycle = took
+	metrics.cycles++
+	metrics.cycleSecSum += took.Seconds()
+}
+
+func metricsCountAction(action string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.Lock()
+	metrics.actions[[2]string{action, result}]++
+	metrics.Unlock()
+}
+
+// promLabel escapes a label value for the text exposition format.
+func promLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func promFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+func promBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// promWriter groups samples under their HELP/TYPE header.
+type promWriter struct{ buf bytes.Buffer }
+
+func (p *promWriter) header(name, typ, help string) {
+	fmt.Fprintf(&p.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (p *promWriter) sample(name, labels, value string) {
+	if labels != "" {
+		fmt.Fprintf(&p.buf, "%s{%s} %s\n", name, labels, value)
+		return
+	}
+	fmt.Fprintf(&p.buf, "%s %s\n", name, value)
+}
+
+var serviceStates = []string{"up", "down", "degraded", "maintenance"}
+
+// metricsHandler renders the Prometheus text format (version 0.0.4).
+// When metrics_token is configured a matching Bearer token is required.
+// Private services are left out for anonymous requests: without a token
+// they need a login session, a matching token counts as authenticated.
+func metricsHandler(infos []ServiceInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tok := appCfg.MetricsToken; tok != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+tok)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		anon := appCfg.MetricsToken == "" && authUser(r) == ""
+		var services []Service
+		metrics.Lock()
+		for _, s := range metrics.latest {
+			if !anon || !isPrivateService(infos, s.Name) {
+				services = append(services, s)
+			}
+		}
+		lastCycle, cycles, cycleSum := metrics.lastCycle, metrics.cycles, metrics.cycleSecSum
+		actions := make(map[[2]string]uint64, len(metrics.actions))
+		for k, v := range metrics.actions {
+			actions[k] = v
+		}
+		metrics.Unlock()
+
+		var p promWriter
+		labels := make([]string, len(services))
+		for i, s := range services {
+			labels[i] = fmt.Sprintf(`service="%s",type="%s"`, promLabel(s.Name), promLabel(s.Type))
+		}
+
+		p.header("spm_service_up", "gauge", "Whether the service check passed (1) or failed (0).")
+		for i, s := range services {
+			p.sample("spm_service_up", labels[i], promBool(s.Active))
+		}
+		p.header("spm_service_state", "gauge", "Current service state, one series per state.")
+		for i, s := range services {
+			for _, st := range serviceStates {
+				p.sample("spm_service_state", labels[i]+`,state="`+st+`"`, promBool(s.State == st))
+			}
+		}
+		p.header("spm_service_check_latency_seconds", "gauge", "Duration of the last check for typed checks (http, ping).")
+		for i, s := range services {
+			if s.LatencyMs > 0 {
+				p.sample("spm_service_check_latency_seconds", labels[i], promFloat(s.LatencyMs/1000))
+			}
+		}
+		p.header("spm_service_last_change_timestamp_seconds", "gauge", "Unix time of the last state change.")
+		for i, s := range services {
+			if !s.LastChanged.IsZero() {
+				p.sample("spm_service_last_change_timestamp_seconds", labels[i], strconv.FormatInt(s.LastChanged.Unix(), 10))
+			}
+		}
+		p.header("spm_service_uptime_ratio", "gauge", "Availability over the window from stored history (0..1).")
+		for i, s := range services {
+			for _, win := range uptimeWindows {
+				if v, ok := s.Uptime[win.Label]; ok {
+					p.sample("spm_service_uptime_ratio", labels[i]+`,window="`+win.Label+`"`, promFloat(v/100))
+				}
+			}
+		}
+		if runtime.GOOS == "linux" {
+			p.header("spm_systemd_restarts_total", "counter", "NRestarts of the systemd unit.")
+			for i, s := range services {
+				if !s.IsSystemd || s.SystemdName == "" {
+					continue
+				}
+				props, err := systemctlShow(s.SystemdName, "NRestarts")
+				if err != nil || props["NRestarts"] == "" {
+					continue
+				}
+				p.sample("spm_systemd_restarts_total", labels[i]+`,unit="`+promLabel(s.SystemdName)+`"`, props["NRestarts"])
+			}
+		}
+
+		p.header("spm_check_cycle_duration_seconds", "gauge", "Duration of the last full check loop.")
+		p.sample("spm_check_cycle_duration_seconds", "", promFloat(lastCycle.Seconds()))
+		p.header("spm_check_cycles_total", "counter", "Completed check loops.")
+		p.sample("spm_check_cycles_total", "", strconv.FormatUint(cycles, 10))
+		p.header("spm_check_cycle_seconds_total", "counter", "Total time spent in check loops.")
+		p.sample("spm_check_cycle_seconds_total", "", promFloat(cycleSum))
+
+		p.header("spm_actions_total", "counter", "Start/stop actions by result.")
+		keys := make([][2]string, 0, len(actions))
+		for k := range actions {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		for _, k := range keys {
+			p.sample("spm_actions_total", fmt.Sprintf(`action="%s",result="%s"`, promLabel(k[0]), k[1]), strconv.FormatUint(actions[k], 10))
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(p.buf.Bytes())
+	}
+}
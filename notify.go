@@ -0,0 +1,770 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is what notifiers receive. Status events are "down", "degraded"
+// and "up" (recovery, with the outage Duration); alert policies repeat
+// "down" as reminders or escalations. "action" is a start/stop (User,
+// Action, Result), "ack" an acknowledged incident and "test" is sent from
+// the API. "digest" carries the events held back during quiet hours.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Service    string    `json:"service"`
+	State      string    `json:"state,omitempty"`
+	PrevState  string    `json:"prev_state,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Link       string    `json:"link,omitempty"`
+	Image      string    `json:"image,omitempty"`
+	Time       time.Time `json:"time"`
+	Duration   int64     `json:"duration_seconds,omitempty"`
+	IncidentID string    `json:"incident_id,omitempty"`
+	Host       string    `json:"host"`
+	User       string    `json:"user,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	Reminder   int       `json:"reminder,omitempty"` // n-th repeat of a down alert
+	Escalated  bool      `json:"escalated,omitempty"`
+	Critical   bool      `json:"critical,omitempty"` // service is marked critical
+	Digest     []Event   `json:"digest,omitempty"`
+}
+
+var eventEmoji = map[string]string{
+	"down": "🔴", "degraded": "🟡", "up": "🟢", "action": "🔧", "ack": "✅", "test": "🔔", "digest": "🌙",
+}
+
+// eventHeadline is the one-line summary without emoji.
+func eventHeadline(ev Event) string {
+	switch {
+	case ev.Type == "up":
+		return ev.Service + " recovered"
+	case ev.Type == "action":
+		return fmt.Sprintf("%s: %s by %s", ev.Service, ev.Action, ev.User)
+	case ev.Type == "ack":
+		return fmt.Sprintf("%s acknowledged by %s", ev.Service, ev.User)
+	case ev.Type == "test":
+		return "Test notification"
+	case ev.Type == "digest":
+		return "Quiet hours digest: " + eventSummary(ev.Digest)
+	case ev.Escalated:
+		return fmt.Sprintf("%s %s for %s, not acknowledged", ev.Service, strings.ToUpper(ev.State), formatDurationSec(ev.Duration))
+	case ev.Reminder > 0:
+		return fmt.Sprintf("%s is still %s (reminder %d)", ev.Service, strings.ToUpper(ev.State), ev.Reminder)
+	default:
+		return ev.Service + " is " + strings.ToUpper(ev.State)
+	}
+}
+
+// eventTitle is the one-line summary used by chat and mail notifiers.
+func eventTitle(ev Event) string {
+	e := eventEmoji[ev.Type]
+	if ev.Escalated {
+		e = "🚨"
+	}
+	if e == "" {
+		return eventHeadline(ev)
+	}
+	return e + " " + eventHeadline(ev)
+}
+
+// eventSummary counts events by type: "3 down, 1 recovered".
+func eventSummary(evs []Event) string {
+	counts := map[string]int{}
+	for _, ev := range evs {
+		counts[ev.Type]++
+	}
+	var parts []string
+	for _, t := range []string{"down", "degraded", "up", "action", "ack", "test"} {
+		if n := counts[t]; n > 0 {
+			label := t
+			switch t {
+			case "up":
+				label = "recovered"
+			case "action":
+				label = "actions"
+			case "ack":
+				label = "acknowledged"
+			case "test":
+				label = "tests"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, label))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// a digest lists at most this many events and bytes, which keeps it well
+// within chat and push message limits (Telegram, Discord and ntfy: 4096)
+const (
+	digestMaxLines = 30
+	digestMaxBytes = 3000
+)
+
+// eventLines are the details shown under the title, most useful first.
+// A digest lists its events instead.
+func eventLines(ev Event) []string {
+	var lines []string
+	if ev.Type == "digest" {
+		size := 0
+		for i, d := range ev.Digest {
+			l := d.Time.Local().Format("15:04") + " " + eventTitle(d)
+			if i == digestMaxLines || size+len(l) > digestMaxBytes {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(ev.Digest)-i))
+				break
+			}
+			size += len(l) + 1
+			lines = append(lines, l)
+		}
+		return append(lines, ev.Host)
+	}
+	if ev.Reason != "" {
+		lines = append(lines, ev.Reason)
+	}
+	if ev.Detail != "" {
+		lines = append(lines, ev.Detail)
+	}
+	if ev.Type == "up" && ev.Duration > 0 {
+		lines = append(lines, "Outage: "+formatDurationSec(ev.Duration))
+	} else if ev.Type == "down" && ev.Duration > 0 {
+		lines = append(lines, "Down for: "+formatDurationSec(ev.Duration))
+	}
+	if ev.Type == "action" && ev.Result != "" {
+		lines = append(lines, "Result: "+ev.Result)
+	}
+	lines = append(lines, ev.Host+" · "+ev.Time.Local().Format("2006-01-02 15:04:05"))
+	return lines
+}
+
+// defaultEvents is what a notifier gets when it lists no events.
+var defaultEvents = []string{"down", "degraded", "up"}
+
+// NotifierConfig is one notification channel in config.json. Services,
+// Tags and Events filter what it receives (empty = everything / the
+// default events). Failed sends are retried Retries times, waiting
+// Backoff and doubling it each time. During QuietHours only events of
+// critical services are sent, the rest follow as one digest.
+type NotifierConfig struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"` // webhook, telegram, smtp, ntfy, gotify
+	Services   []string        `json:"services,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
+	Events     []string        `json:"events,omitempty"`
+	Retries    *int            `json:"retries,omitempty"` // default 3
+	Backoff    string          `json:"backoff,omitempty"` // default 5s
+	QuietHours *QuietHours     `json:"quiet_hours,omitempty"`
+	Webhook    *WebhookConfig  `json:"webhook,omitempty"`
+	Telegram   *TelegramConfig `json:"telegram,omitempty"`
+	SMTP       *SMTPConfig     `json:"smtp,omitempty"`
+	Ntfy       *NtfyConfig     `json:"ntfy,omitempty"`
+	Gotify     *GotifyConfig   `json:"gotify,omitempty"`
+}
+
+func (c NotifierConfig) wants(ev Event) bool {
+	return c.rejects(ev) == ""
+}
+
+// rejects explains why the notifier's own filters drop ev ("" = they don't).
+func (c NotifierConfig) rejects(ev Event) string {
+	events := c.Events
+	if len(events) == 0 {
+		events = defaultEvents
+	}
+	if ev.Type != "test" && !containsFold(events, ev.Type) {
+		return "event type not in events"
+	}
+	if len(c.Services) == 0 && len(c.Tags) == 0 {
+		return ""
+	}
+	if containsFold(c.Services, ev.Service) || hasAnyTag(ev.Tags, c.Tags) {
+		return ""
+	}
+	return "service not in services / tags"
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// sender delivers one event over a channel.
+type sender interface {
+	send(ev Event) error
+}
+
+// batchSender is a sender that can combine events arriving within its
+// batch window into one message (email).
+type batchSender interface {
+	sender
+	batchWindow() time.Duration
+	sendBatch(evs []Event) error
+}
+
+// partialError is a send that reached some recipients only: failed holds
+// the indices (into the events sent) of those not fully delivered and
+// retry resends just the parts that failed.
+type partialError struct {
+	err    error
+	failed map[int]bool
+	retry  func() error
+}
+
+func (e *partialError) Error() string { return e.err.Error() }
+
+type notifier struct {
+	cfg     NotifierConfig
+	sender  sender
+	queue   chan Event
+	retries int
+	backoff time.Duration
+	quiet   *MaintenanceWindow // quiet hours as a recurring window
+	held    []Event            // waiting for the end of quiet hours
+}
+
+// QuietHours is a daily period from Start to End (HH:MM in Timezone,
+// default local; an End before Start runs past midnight). Days limits it
+// to the days it starts on (mon..sun; empty = daily).
+type QuietHours struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Days     []string `json:"days,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// window converts quiet hours to a recurring maintenance-style window.
+func (q QuietHours) window() (*MaintenanceWindow, error) {
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours start must be HH:MM")
+	}
+	end, err := time.Parse("15:04", q.End)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours end must be HH:MM")
+	}
+	d := end.Sub(start)
+	if d <= 0 {
+		d += 24 * time.Hour
+	}
+	m := &MaintenanceWindow{Recurring: &MaintenanceRecurrence{Days: q.Days, Start: q.Start, Duration: d.String(), Timezone: q.Timezone}}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("quiet_hours: %v", err)
+	}
+	return m, nil
+}
+
+// quietAt reports whether ev has to wait for the end of quiet hours.
+func (n *notifier) quietAt(ev Event, now time.Time) bool {
+	if n.quiet == nil || ev.Critical || ev.Type == "test" {
+		return false
+	}
+	_, active := n.quiet.activeUntil(now)
+	return active
+}
+
+// heldKeep bounds the events a notifier holds during quiet hours.
+const heldKeep = 1000
+
+// events held during quiet hours by notifier name, kept in held.json next
+// to the delivery log so a restart does not lose them
+var heldEvents = struct {
+	sync.Mutex
+	path   string
+	byName map[string][]Event
+}{byName: map[string][]Event{}}
+
+// loadHeld reads the held events of the configured notifiers.
+func loadHeld(path string, names map[string]bool) {
+	heldEvents.Lock()
+	defer heldEvents.Unlock()
+	heldEvents.path = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var byName map[string][]Event
+	if err := json.Unmarshal(data, &byName); err != nil {
+		log.Printf("held events file parse error, starting empty: %v", err)
+		return
+	}
+	for name, evs := range byName {
+		if names[name] && len(evs) > 0 {
+			heldEvents.byName[name] = slices.Clone(evs)
+		}
+	}
+}
+
+// saveHeld stores the events a notifier currently holds.
+func saveHeld(name string, evs []Event) {
+	heldEvents.Lock()
+	defer heldEvents.Unlock()
+	if heldEvents.path == "" {
+		return
+	}
+	if len(evs) == 0 {
+		delete(heldEvents.byName, name)
+	} else {
+		heldEvents.byName[name] = slices.Clone(evs)
+	}
+	data, err := json.Marshal(heldEvents.byName)
+	if err != nil {
+		return
+	}
+	if err := osWriteAtomic(heldEvents.path, data); err != nil {
+		log.Printf("held events write error: %v", err)
+	}
+}
+
+// Delivery is one attempt to hand an event to a notifier.
+type Delivery struct {
+	Time     time.Time `json:"time"`
+	Notifier string    `json:"notifier"`
+	EventID  string    `json:"event_id"`
+	Event    string    `json:"event"`
+	Service  string    `json:"service"`
+	Attempt  int       `json:"attempt"`
+	OK       bool      `json:"ok"`
+	Error    string    `json:"error,omitempty"`
+}
+
+const deliveryKeep = 500
+
+// configured notifiers and the recent delivery log
+var notifications = struct {
+	sync.RWMutex
+	list       []*notifier
+	deliveries []Delivery // oldest first, at most deliveryKeep
+	logPath    string
+	logLines   int // entries in the log file; rewritten past 2*deliveryKeep
+	infos      []ServiceInfo
+}{}
+
+// notifyServiceInfo returns the configured service (tags, link, image,
+// critical) an event is about.
+func notifyServiceInfo(name string) ServiceInfo {
+	notifications.RLock()
+	defer notifications.RUnlock()
+	for _, si := range notifications.infos {
+		if si.Name == name {
+			return si
+		}
+	}
+	return ServiceInfo{Name: name}
+}
+
+func newSender(c NotifierConfig) (sender, error) {
+	switch c.Type {
+	case "webhook":
+		if c.Webhook == nil {
+			return nil, fmt.Errorf("webhook settings missing")
+		}
+		return newWebhookSender(*c.Webhook)
+	case "telegram":
+		if c.Telegram == nil {
+			return nil, fmt.Errorf("telegram settings missing")
+		}
+		return newTelegramSender(*c.Telegram)
+	case "smtp":
+		if c.SMTP == nil {
+			return nil, fmt.Errorf("smtp settings missing")
+		}
+		return newSMTPSender(*c.SMTP)
+	case "ntfy":
+		if c.Ntfy == nil {
+			return nil, fmt.Errorf("ntfy settings missing")
+		}
+		return newNtfySender(*c.Ntfy)
+	case "gotify":
+		if c.Gotify == nil {
+			return nil, fmt.Errorf("gotify settings missing")
+		}
+		return newGotifySender(*c.Gotify)
+	default:
+		return nil, fmt.Errorf("unknown type %q", c.Type)
+	}
+}
+
+// setupNotifiers starts a worker per valid notifier and loads the tail of
+// the delivery log. infos are the local services events refer to.
+func setupNotifiers(cfgs []NotifierConfig, infos []ServiceInfo, logPath string) {
+	notifications.Lock()
+	defer notifications.Unlock()
+	notifications.infos = infos
+	notifications.logPath = logPath
+	_ = os.MkdirAll(filepath.Dir(logPath), 0755)
+	notifications.deliveries = readDeliveries(logPath, deliveryKeep)
+	notifications.logLines = len(notifications.deliveries)
+	names := map[string]bool{}
+	for _, c := range cfgs {
+		if c.QuietHours != nil {
+			names[c.Name] = true
+		}
+	}
+	loadHeld(filepath.Join(filepath.Dir(logPath), "held.json"), names)
+	for _, c := range cfgs {
+		if c.Name == "" {
+			log.Printf("notifier ignored: name is required")
+			continue
+		}
+		s, err := newSender(c)
+		if err != nil {
+			log.Printf("notifier %s disabled: %v", c.Name, err)
+			continue
+		}
+		n := &notifier{cfg: c, sender: s, queue: make(chan Event, 100), retries: 3, backoff: parseDurationDefault(c.Backoff, 5*time.Second)}
+		if c.Retries != nil && *c.Retries >= 0 {
+			n.retries = *c.Retries
+		}
+		if c.QuietHours != nil {
+			if n.quiet, err = c.QuietHours.window(); err != nil {
+				log.Printf("notifier %s disabled: %v", c.Name, err)
+				continue
+			}
+			n.held = heldEvents.byName[c.Name]
+		}
+		notifications.list = append(notifications.list, n)
+		go n.run()
+	}
+}
+
+func (n *notifier) run() {
+	bs, batching := n.sender.(batchSender)
+	var tick <-chan time.Time
+	if n.quiet != nil {
+		t := time.NewTicker(30 * time.Second)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case ev := <-n.queue:
+			if n.quietAt(ev, time.Now()) {
+				n.hold(ev)
+				continue
+			}
+			evs := []Event{ev}
+			if batching && bs.batchWindow() > 0 {
+				evs = n.collect(evs, bs.batchWindow())
+			}
+			n.deliver(evs)
+		case now := <-tick:
+			if len(n.held) > 0 && !n.quietAt(Event{}, now) {
+				n.deliverDigest(now)
+			}
+		}
+	}
+}
+
+func (n *notifier) hold(ev Event) {
+	if len(n.held) == heldKeep {
+		log.Printf("notifier %s: quiet hours queue full, dropped %s %s", n.cfg.Name, n.held[0].Type, n.held[0].Service)
+		n.held = n.held[1:]
+	}
+	n.held = append(n.held, ev)
+	saveHeld(n.cfg.Name, n.held)
+}
+
+// deliverDigest sends what was held during quiet hours: as one batch for
+// a batchSender (one email), else as a single "digest" event.
+func (n *notifier) deliverDigest(now time.Time) {
+	held := n.held
+	n.held = nil
+	saveHeld(n.cfg.Name, nil)
+	if _, ok := n.sender.(batchSender); ok {
+		n.deliver(held)
+		return
+	}
+	n.deliver([]Event{{ID: newToken()[:12], Type: "digest", Time: now, Host: monitorHost, Digest: held}})
+}
+
+// collect adds everything queued within window to evs, holding what
+// arrives during quiet hours like run does.
+func (n *notifier) collect(evs []Event, window time.Duration) []Event {
+	t := time.NewTimer(window)
+	defer t.Stop()
+	for {
+		select {
+		case ev, ok := <-n.queue:
+			if !ok {
+				return evs
+			}
+			if n.quietAt(ev, time.Now()) {
+				n.hold(ev)
+				continue
+			}
+			evs = append(evs, ev)
+		case <-t.C:
+			return evs
+		}
+	}
+}
+
+// deliver sends evs (several only for a batchSender) with retries and
+// records one delivery per event and attempt. After a partialError only
+// the failed part is retried and recorded again.
+func (n *notifier) deliver(evs []Event) {
+	send := func() error {
+		if len(evs) == 1 {
+			return n.sender.send(evs[0])
+		}
+		return n.sender.(batchSender).sendBatch(evs)
+	}
+	pending := make([]int, len(evs))
+	for i := range pending {
+		pending[i] = i
+	}
+	wait := n.backoff
+	for attempt := 1; ; attempt++ {
+		err := send()
+		var pe *partialError
+		if errors.As(err, &pe) {
+			send = pe.retry
+		}
+		var failed []int
+		for _, i := range pending {
+			ev := evs[i]
+			d := Delivery{Time: time.Now(), Notifier: n.cfg.Name, EventID: ev.ID, Event: ev.Type, Service: ev.Service, Attempt: attempt, OK: true}
+			if err != nil && (pe == nil || pe.failed[i]) {
+				d.OK, d.Error = false, err.Error()
+				failed = append(failed, i)
+			}
+			recordDelivery(d)
+		}
+		if len(failed) == 0 {
+			return
+		}
+		pending = failed
+		if attempt > n.retries {
+			if len(failed) == 1 {
+				log.Printf("notifier %s: giving up on %s %s: %v", n.cfg.Name, evs[failed[0]].Type, evs[failed[0]].Service, err)
+			} else {
+				log.Printf("notifier %s: giving up on %d events of a batch: %v", n.cfg.Name, len(failed), err)
+			}
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// notify hands ev to every notifier whose filters and routing rules
+// match.
+func notify(ev Event) {
+	ev = prepareEvent(ev)
+	targets := routeEvent(ev).Targets
+	dispatch(ev, func(n *notifier) bool { return n.accepts(ev, targets) })
+}
+
+// prepareEvent fills in the id, host and critical flag of ev.
+func prepareEvent(ev Event) Event {
+	if ev.ID == "" {
+		ev.ID = newToken()[:12]
+	}
+	if ev.Host == "" {
+		ev.Host = monitorHost
+	}
+	if notifyServiceInfo(ev.Service).Critical {
+		ev.Critical = true
+	}
+	return ev
+}
+
+// dispatch hands ev to the notifiers selected by match. It never blocks
+// the check loop: a full queue drops the event (and logs it).
+func dispatch(ev Event, match func(n *notifier) bool) {
+	ev = prepareEvent(ev)
+	notifications.RLock()
+	defer notifications.RUnlock()
+	for _, n := range notifications.list {
+		if !match(n) {
+			continue
+		}
+		select {
+		case n.queue <- ev:
+		default:
+			log.Printf("notifier %s: queue full, dropped %s %s", n.cfg.Name, ev.Type, ev.Service)
+		}
+	}
+}
+
+// statusEvent maps a state transition to an event type; "" means no
+// notification (first observation, maintenance on either side).
+func statusEvent(prev, curr string) string {
+	if prev == "" || prev == curr || prev == "maintenance" || curr == "maintenance" {
+		return ""
+	}
+	switch curr {
+	case "down", "degraded":
+		return curr
+	case "up":
+		return "up"
+	}
+	return ""
+}
+
+// notifyStatusChange is called from detectAndLogStatusChanges. in is the
+// incident opened or closed by this transition, if any. A freshly opened
+// one is always reported as down, so a service that is still down when the
+// monitor starts or when its maintenance window ends alerts as well.
+func notifyStatusChange(s Service, prev string, in *Incident, now time.Time) {
+	typ := statusEvent(prev, s.State)
+	if typ == "" && in != nil && in.open() {
+		typ = "down"
+	}
+	if typ == "" {
+		return
+	}
+	ev := Event{Type: typ, Service: s.Name, State: s.State, PrevState: prev, Reason: s.Reason, Detail: s.Detail,
+		Tags: s.Tags, Link: s.Link, Image: s.Image, Time: now}
+	if in != nil {
+		ev.IncidentID = in.ID
+		if !in.open() {
+			ev.Duration = in.Duration
+		}
+	}
+	notify(ev)
+}
+
+// notifyAction reports a start/stop attempt ("action" event).
+func notifyAction(si ServiceInfo, kind, user, result string, now time.Time) {
+	notify(Event{Type: "action", Service: si.Name, Tags: si.Tags, Link: si.Link, Image: si.Image,
+		Time: now, User: user, Action: kind, Result: result})
+}
+
+func recordDelivery(d Delivery) {
+	notifications.Lock()
+	defer notifications.Unlock()
+	notifications.deliveries = append(notifications.deliveries, d)
+	if over := len(notifications.deliveries) - deliveryKeep; over > 0 {
+		notifications.deliveries = append([]Delivery(nil), notifications.deliveries[over:]...)
+	}
+	if notifications.logPath == "" {
+		return
+	}
+	if notifications.logLines >= 2*deliveryKeep {
+		// the ring already holds d: the file becomes the last deliveryKeep
+		if err := writeDeliveries(notifications.logPath, notifications.deliveries); err != nil {
+			log.Printf("delivery log: %v", err)
+			return
+		}
+		notifications.logLines = len(notifications.deliveries)
+		return
+	}
+	line, _ := json.Marshal(d)
+	f, err := os.OpenFile(notifications.logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("delivery log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err == nil {
+		notifications.logLines++
+	}
+}
+
+func writeDeliveries(path string, ds []Delivery) error {
+	var b strings.Builder
+	for _, d := range ds {
+		line, _ := json.Marshal(d)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return osWriteAtomic(path, []byte(b.String()))
+}
+
+// readDeliveries returns the last n entries of the delivery log and
+// rewrites the file to just those.
+func readDeliveries(path string, n int) []Delivery {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	var res []Delivery
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var d Delivery
+		if json.Unmarshal(sc.Bytes(), &d) == nil {
+			res = append(res, d)
+		}
+	}
+	f.Close()
+	if len(res) > n {
+		res = res[len(res)-n:]
+		_ = writeDeliveries(path, res)
+	}
+	return res
+}
+
+// handleNotifications serves GET /api/notifications (configured notifiers
+// and the latest deliveries, newest first) and POST ?test=<name>, which
+// sends a test event. Login required.
+func handleNotifications(w http.ResponseWriter, r *http.Request) {
+	user := authUser(r)
+	if user == "" {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		type notifierJSON struct {
+			Name   string   `json:"name"`
+			Type   string   `json:"type"`
+			Events []string `json:"events"`
+		}
+		notifications.RLock()
+		list := []notifierJSON{}
+		for _, n := range notifications.list {
+			ev := n.cfg.Events
+			if len(ev) == 0 {
+				ev = defaultEvents
+			}
+			list = append(list, notifierJSON{Name: n.cfg.Name, Type: n.cfg.Type, Events: ev})
+		}
+		recent := make([]Delivery, 0, 100)
+		for i := len(notifications.deliveries) - 1; i >= 0 && len(recent) < 100; i-- {
+			recent = append(recent, notifications.deliveries[i])
+		}
+		notifications.RUnlock()
+		respondJSON(w, map[string]any{"notifiers": list, "deliveries": recent})
+	case http.MethodPost:
+		name := r.URL.Query().Get("test")
+		notifications.RLock()
+		var target *notifier
+		for _, n := range notifications.list {
+			if n.cfg.Name == name {
+				target = n
+			}
+		}
+		notifications.RUnlock()
+		if target == nil {
+			respondJSONCode(w, http.StatusNotFound, map[string]string{"error": "notifier not found"})
+			return
+		}
+		ev := Event{ID: newToken()[:12], Type: "test", Service: "SP Monitor", State: "up", Reason: "test notification from " + user, Time: time.Now(), Host: monitorHost}
+		select {
+		case target.queue <- ev:
+			respondJSON(w, map[string]any{"ok": true, "event_id": ev.ID})
+		default:
+			respondJSONCode(w, http.StatusServiceUnavailable, map[string]string{"error": "queue full"})
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
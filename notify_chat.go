@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Native payloads for chat incoming webhooks (WebhookConfig.Format):
+// "slack" and "mattermost" use Slack attachments, "discord" uses embeds.
+
+// event colours, same palette as the badges
+var eventColor = map[string]int{
+	"down":     0xe05d44,
+	"degraded": 0xdfb317,
+	"up":       0x44cc11,
+	"action":   0x007ec6,
+	"ack":      0x007ec6,
+	"test":     0x9f9f9f,
+	"digest":   0x9f9f9f,
+}
+
+type chatField struct {
+	Name  string
+	Value string
+	Short bool
+}
+
+// chatFields are the structured details of an event.
+func chatFields(ev Event) []chatField {
+	var fs []chatField
+	switch ev.Type {
+	case "action":
+		fs = append(fs, chatField{"Action", ev.Action, true}, chatField{"User", ev.User, true})
+		if ev.Result != "" {
+			fs = append(fs, chatField{"Result", ev.Result, false})
+		}
+	case "ack":
+		fs = append(fs, chatField{"User", ev.User, true}, chatField{"Incident", ev.IncidentID, true})
+	case "test", "digest":
+	default:
+		state := strings.ToUpper(ev.State)
+		if ev.PrevState != "" {
+			state = strings.ToUpper(ev.PrevState) + " → " + state
+		}
+		fs = append(fs, chatField{"State", state, true})
+		if ev.Type == "up" && ev.Duration > 0 {
+			fs = append(fs, chatField{"Outage", formatDurationSec(ev.Duration), true})
+		} else if ev.Duration > 0 {
+			fs = append(fs, chatField{"Down for", formatDurationSec(ev.Duration), true})
+		}
+		if ev.Reason != "" {
+			fs = append(fs, chatField{"Reason", ev.Reason, false})
+		}
+		if ev.Detail != "" {
+			fs = append(fs, chatField{"Detail", ev.Detail, false})
+		}
+		if ev.IncidentID != "" {
+			fs = append(fs, chatField{"Incident", ev.IncidentID, true})
+		}
+	}
+	if len(ev.Tags) > 0 {
+		fs = append(fs, chatField{"Tags", strings.Join(ev.Tags, ", "), true})
+	}
+	return fs
+}
+
+// digestText lists the events of a digest, one per line.
+func digestText(ev Event) string {
+	if ev.Type != "digest" {
+		return ""
+	}
+	lines := eventLines(ev)
+	return strings.Join(lines[:len(lines)-1], "\n")
+}
+
+// absURL keeps only links chat services can open (not site-relative ones).
+func absURL(u string) string {
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	return ""
+}
+
+func slackPayload(ev Event) ([]byte, error) {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Fallback  string  `json:"fallback"`
+		Color     string  `json:"color"`
+		Title     string  `json:"title"`
+		TitleLink string  `json:"title_link,omitempty"`
+		Text      string  `json:"text,omitempty"`
+		ThumbURL  string  `json:"thumb_url,omitempty"`
+		Fields    []field `json:"fields,omitempty"`
+		Footer    string  `json:"footer"`
+		Ts        int64   `json:"ts"`
+	}
+	a := attachment{
+		Fallback:  eventTitle(ev),
+		Color:     fmt.Sprintf("#%06x", eventColor[ev.Type]),
+		Title:     eventTitle(ev),
+		TitleLink: absURL(ev.Link),
+		ThumbURL:  absURL(ev.Image),
+		Footer:    "SP Monitor · " + ev.Host,
+		Ts:        ev.Time.Unix(),
+		Text:      digestText(ev),
+	}
+	for _, f := range chatFields(ev) {
+		a.Fields = append(a.Fields, field{f.Name, f.Value, f.Short})
+	}
+	return json.Marshal(map[string]any{"attachments": []attachment{a}})
+}
+
+func discordPayload(ev Event) ([]byte, error) {
+	type field struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+	type link struct {
+		URL string `json:"url"`
+	}
+	type embed struct {
+		Title       string  `json:"title"`
+		URL         string  `json:"url,omitempty"`
+		Description string  `json:"description,omitempty"`
+		Color       int     `json:"color"`
+		Fields      []field `json:"fields,omitempty"`
+		Thumbnail   *link   `json:"thumbnail,omitempty"`
+		Footer      struct {
+			Text string `json:"text"`
+		} `json:"footer"`
+		Timestamp string `json:"timestamp"`
+	}
+	e := embed{Title: eventTitle(ev), URL: absURL(ev.Link), Description: digestText(ev), Color: eventColor[ev.Type], Timestamp: ev.Time.UTC().Format(time.RFC3339)}
+	if img := absURL(ev.Image); img != "" {
+		e.Thumbnail = &link{URL: img}
+	}
+	e.Footer.Text = "SP Monitor · " + ev.Host
+	for _, f := range chatFields(ev) {
+		// Discord rejects empty field values
+		if f.Value != "" {
+			e.Fields = append(e.Fields, field{f.Name, f.Value, f.Short})
+		}
+	}
+	return json.Marshal(map[string]any{"embeds": []embed{e}})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Push notifications for phones: ntfy and Gotify. Priority maps event
+// types to the server's priority scale, overriding the defaults below.
+
+// NtfyConfig publishes to a topic of an ntfy server. Token is sent as a
+// Bearer token, Username/Password as basic auth.
+type NtfyConfig struct {
+	Server   string         `json:"server,omitempty"` // default https://ntfy.sh
+	Topic    string         `json:"topic"`
+	Token    string         `json:"token,omitempty"`
+	Username string         `json:"username,omitempty"`
+	Password string         `json:"password,omitempty"`
+	Priority map[string]int `json:"priority,omitempty"` // 1 (min) … 5 (urgent)
+	Timeout  string         `json:"timeout,omitempty"`  // default 10s
+}
+
+// GotifyConfig posts to a Gotify server with an application token.
+type GotifyConfig struct {
+	Server   string         `json:"server"`
+	Token    string         `json:"token"`
+	Priority map[string]int `json:"priority,omitempty"` // 0 … 10
+	Timeout  string         `json:"timeout,omitempty"`  // default 10s
+}
+
+var (
+	ntfyPriority   = map[string]int{"down": 4, "degraded": 3, "up": 3, "action": 2, "ack": 2, "test": 3, "digest": 3}
+	gotifyPriority = map[string]int{"down": 8, "degraded": 5, "up": 4, "action": 2, "ack": 2, "test": 4, "digest": 4}
+)
+
+// ntfy tags that render as emoji, by event type
+var ntfyEmoji = map[string]string{
+	"down": "rotating_light", "degraded": "warning", "up": "white_check_mark", "action": "wrench", "ack": "ok_hand", "test": "bell", "digest": "zzz",
+}
+
+func eventPriority(overrides, defaults map[string]int, typ string) int {
+	if p, ok := overrides[typ]; ok {
+		return p
+	}
+	return defaults[typ]
+}
+
+type ntfySender struct {
+	cfg     NtfyConfig
+	headers map[string]string
+	client  *http.Client
+}
+
+func newNtfySender(c NtfyConfig) (*ntfySender, error) {
+	if c.Topic == "" {
+		return nil, fmt.Errorf("ntfy topic is required")
+	}
+	c.Server = strings.TrimRight(c.Server, "/")
+	if c.Server == "" {
+		c.Server = "https://ntfy.sh"
+	}
+	ns := &ntfySender{cfg: c, headers: map[string]string{}, client: &http.Client{Timeout: parseDurationDefault(c.Timeout, 10*time.Second)}}
+	if c.Token != "" {
+		ns.headers["Authorization"] = "Bearer " + c.Token
+	} else if c.Username != "" {
+		ns.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	}
+	return ns, nil
+}
+
+func (ns *ntfySender) send(ev Event) error {
+	msg := map[string]any{
+		"topic":    ns.cfg.Topic,
+		"title":    eventHeadline(ev), // ntfy shows the emoji tag
+		"message":  strings.Join(eventLines(ev), "\n"),
+		"priority": eventPriority(ns.cfg.Priority, ntfyPriority, ev.Type),
+		"tags":     append([]string{ntfyEmoji[ev.Type]}, ev.Tags...),
+	}
+	if ev.Escalated {
+		msg["priority"] = 5
+		msg["tags"] = append([]string{"rotating_light", "sos"}, ev.Tags...)
+	}
+	if link := absURL(ev.Link); link != "" {
+		msg["click"] = link
+	}
+	if img := absURL(ev.Image); img != "" {
+		msg["icon"] = img
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return postSigned(ns.client, http.MethodPost, ns.cfg.Server, "application/json", ns.headers, "", ev, body)
+}
+
+type gotifySender struct {
+	cfg    GotifyConfig
+	client *http.Client
+}
+
+func newGotifySender(c GotifyConfig) (*gotifySender, error) {
+	if c.Server == "" || c.Token == "" {
+		return nil, fmt.Errorf("gotify server and token are required")
+	}
+	c.Server = strings.TrimRight(c.Server, "/")
+	return &gotifySender{cfg: c, client: &http.Client{Timeout: parseDurationDefault(c.Timeout, 10*time.Second)}}, nil
+}
+
+func (gs *gotifySender) send(ev Event) error {
+	msg := map[string]any{
+		"title":    eventTitle(ev),
+		"message":  strings.Join(eventLines(ev), "\n"),
+		"priority": eventPriority(gs.cfg.Priority, gotifyPriority, ev.Type),
+	}
+	if link := absURL(ev.Link); link != "" {
+		msg["extras"] = map[string]any{"client::notification": map[string]any{"click": map[string]string{"url": link}}}
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return postSigned(gs.client, http.MethodPost, gs.cfg.Server+"/message", "application/json", map[string]string{"X-Gotify-Key": gs.cfg.Token}, "", ev, body)
+}
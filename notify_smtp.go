@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig sends events by email. TLS is "starttls" (default, port
+// 587), "implicit" (port 465) or "none" (port 25). Events arriving within
+// Batch of each other (default 10s, "0" = off) go out as one message per
+// recipient.
+type SMTPConfig struct {
+	Host          string      `json:"host"`
+	Port          int         `json:"port,omitempty"`
+	TLS           string      `json:"tls,omitempty"`
+	Username      string      `json:"username,omitempty"`
+	Password      string      `json:"password,omitempty"`
+	From          string      `json:"from"`
+	To            []string    `json:"to,omitempty"`
+	Routes        []SMTPRoute `json:"routes,omitempty"`
+	SubjectPrefix string      `json:"subject_prefix,omitempty"` // default "[SP Monitor]"
+	Batch         string      `json:"batch,omitempty"`
+	Timeout       string      `json:"timeout,omitempty"` // default 10s
+}
+
+// SMTPRoute adds recipients for events of the listed services or tags.
+type SMTPRoute struct {
+	Services []string `json:"services,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	To       []string `json:"to"`
+}
+
+type smtpSender struct {
+	cfg     SMTPConfig
+	batch   time.Duration
+	timeout time.Duration
+}
+
+func newSMTPSender(c SMTPConfig) (*smtpSender, error) {
+	if c.Host == "" || c.From == "" {
+		return nil, fmt.Errorf("smtp host and from are required")
+	}
+	if len(c.To) == 0 && len(c.Routes) == 0 {
+		return nil, fmt.Errorf("smtp needs to or routes")
+	}
+	switch c.TLS {
+	case "":
+		c.TLS = "starttls"
+	case "starttls", "implicit", "none":
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", c.TLS)
+	}
+	if c.Port == 0 {
+		c.Port = map[string]int{"starttls": 587, "implicit": 465, "none": 25}[c.TLS]
+	}
+	if c.SubjectPrefix == "" {
+		c.SubjectPrefix = "[SP Monitor]"
+	}
+	ss := &smtpSender{cfg: c, batch: 10 * time.Second, timeout: parseDurationDefault(c.Timeout, 10*time.Second)}
+	if c.Batch != "" {
+		d, err := time.ParseDuration(c.Batch)
+		if c.Batch == "0" {
+			d, err = 0, nil
+		}
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("bad smtp batch %q", c.Batch)
+		}
+		ss.batch = d
+	}
+	return ss, nil
+}
+
+func (ss *smtpSender) batchWindow() time.Duration { return ss.batch }
+
+// recipients are the default addresses plus those of matching routes.
+func (ss *smtpSender) recipients(ev Event) []string {
+	set := map[string]bool{}
+	for _, a := range ss.cfg.To {
+		set[a] = true
+	}
+	for _, r := range ss.cfg.Routes {
+		if containsFold(r.Services, ev.Service) || hasAnyTag(ev.Tags, r.Tags) {
+			for _, a := range r.To {
+				set[a] = true
+			}
+		}
+	}
+	res := make([]string, 0, len(set))
+	for a := range set {
+		res = append(res, a)
+	}
+	return res
+}
+
+func (ss *smtpSender) send(ev Event) error {
+	return ss.sendBatch([]Event{ev})
+}
+
+// smtpGroup is one email: recipients that get the same events.
+type smtpGroup struct {
+	to  []string
+	evs []int // indices into the batch
+}
+
+// sendBatch writes one email per recipient, merging recipients that get
+// the same events. Events nobody is routed to are skipped.
+func (ss *smtpSender) sendBatch(evs []Event) error {
+	perRcpt := map[string][]int{}
+	for i, ev := range evs {
+		for _, a := range ss.recipients(ev) {
+			perRcpt[a] = append(perRcpt[a], i)
+		}
+	}
+	rcpts := make([]string, 0, len(perRcpt))
+	for a := range perRcpt {
+		rcpts = append(rcpts, a)
+	}
+	sort.Strings(rcpts)
+	var groups []smtpGroup
+	byKey := map[string]int{}
+	for _, a := range rcpts {
+		key := fmt.Sprint(perRcpt[a])
+		if g, ok := byKey[key]; ok {
+			groups[g].to = append(groups[g].to, a)
+			continue
+		}
+		byKey[key] = len(groups)
+		groups = append(groups, smtpGroup{to: []string{a}, evs: perRcpt[a]})
+	}
+	return ss.sendGroups(evs, groups)
+}
+
+// sendGroups sends every group's email. If some fail it returns a
+// partialError whose retry sends only those again, so recipients that
+// already got their email do not get it twice.
+func (ss *smtpSender) sendGroups(evs []Event, groups []smtpGroup) error {
+	var failed []smtpGroup
+	var first error
+	for _, g := range groups {
+		var group []Event
+		for _, i := range g.evs {
+			group = append(group, evs[i])
+		}
+		if err := ss.deliver(g.to, ss.message(g.to, group)); err != nil {
+			failed = append(failed, g)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	pe := &partialError{err: first, failed: map[int]bool{}, retry: func() error { return ss.sendGroups(evs, failed) }}
+	for _, g := range failed {
+		for _, i := range g.evs {
+			pe.failed[i] = true
+		}
+	}
+	return pe
+}
+
+func (ss *smtpSender) message(to []string, evs []Event) []byte {
+	subject := eventTitle(evs[0])
+	if len(evs) > 1 {
+		subject = eventSummary(evs)
+	}
+	var body strings.Builder
+	for i, ev := range evs {
+		if i > 0 {
+			body.WriteString("\r\n")
+		}
+		if len(evs) > 1 {
+			body.WriteString(eventTitle(ev) + "\r\n")
+		}
+		for _, l := range eventLines(ev) {
+			body.WriteString(l + "\r\n")
+		}
+		if ev.Link != "" {
+			body.WriteString(ev.Link + "\r\n")
+		}
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", ss.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", ss.cfg.SubjectPrefix+" "+subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", newToken()[:16], monitorHost)
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(body.String())
+	return msg.Bytes()
+}
+
+func (ss *smtpSender) deliver(to []string, msg []byte) error {
+	addr := net.JoinHostPort(ss.cfg.Host, strconv.Itoa(ss.cfg.Port))
+	dialer := &net.Dialer{Timeout: ss.timeout}
+	tlsCfg := &tls.Config{ServerName: ss.cfg.Host}
+	var conn net.Conn
+	var err error
+	if ss.cfg.TLS == "implicit" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(ss.timeout))
+	c, err := smtp.NewClient(conn, ss.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ss.cfg.TLS == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server does not offer STARTTLS")
+		}
+		if err := c.StartTLS(tlsCfg); err != nil {
+			return err
+		}
+	}
+	if ss.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", ss.cfg.Username, ss.cfg.Password, ss.cfg.Host)); err != nil {
+			return fmt.Errorf("auth: %v", err)
+		}
+	}
+	if err := c.Mail(ss.cfg.From); err != nil {
+		return err
+	}
+	for _, a := range to {
+		if err := c.Rcpt(a); err != nil {
+			return fmt.Errorf("rcpt %s: %v", a, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TelegramConfig sends events through a Telegram bot. APIBase can point
+// at a local Bot API server or a stand-in. With AckButtons, down alerts
+// get an "Acknowledge" button; the monitor then polls getUpdates for the
+// bot, so the bot must not have a webhook set.
+type TelegramConfig struct {
+	BotToken   string   `json:"bot_token"`
+	ChatIDs    []string `json:"chat_ids"`           // numeric IDs or @channel names
+	APIBase    string   `json:"api_base,omitempty"` // default https://api.telegram.org
+	AckButtons bool     `json:"ack_buttons,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"` // default 10s
+}
+
+type telegramSender struct {
+	cfg    TelegramConfig
+	api    *telegramAPI
+	client *http.Client
+}
+
+// telegramAPI calls Bot API methods of one bot.
+type telegramAPI struct {
+	base  string
+	token string
+}
+
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// call posts payload to method and decodes the result into out (if set).
+// Transport errors are unwrapped so the bot token in the URL is not logged.
+func (t *telegramAPI) call(client *http.Client, method string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(t.base+"/bot"+t.token+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("%s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	var tr telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return fmt.Errorf("%s: status %d", method, resp.StatusCode)
+	}
+	if !tr.OK {
+		return fmt.Errorf("%s: %s", method, tr.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(tr.Result, out)
+	}
+	return nil
+}
+
+func newTelegramSender(c TelegramConfig) (*telegramSender, error) {
+	if c.BotToken == "" || len(c.ChatIDs) == 0 {
+		return nil, fmt.Errorf("telegram bot_token and chat_ids are required")
+	}
+	base := strings.TrimRight(c.APIBase, "/")
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	ts := &telegramSender{
+		cfg:    c,
+		api:    &telegramAPI{base: base, token: c.BotToken},
+		client: &http.Client{Timeout: parseDurationDefault(c.Timeout, 10*time.Second)},
+	}
+	if c.AckButtons {
+		startTelegramPoller(ts.api, c.ChatIDs)
+	}
+	return ts, nil
+}
+
+// telegramText renders an event as an HTML message.
+func telegramText(ev Event) string {
+	var b strings.Builder
+	b.WriteString("<b>" + html.EscapeString(eventTitle(ev)) + "</b>")
+	for _, l := range eventLines(ev) {
+		b.WriteString("\n" + html.EscapeString(l))
+	}
+	if ev.Link != "" {
+		b.WriteString("\n<a href=\"" + html.EscapeString(ev.Link) + "\">Open</a>")
+	}
+	return b.String()
+}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type telegramMarkup struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+func (ts *telegramSender) send(ev Event) error {
+	return ts.sendTo(ev, ts.cfg.ChatIDs)
+}
+
+// sendTo delivers to every chat in chats. When some fail it returns a
+// partialError whose retry sends only to those, so chats that already
+// got the message do not get it twice.
+func (ts *telegramSender) sendTo(ev Event, chats []string) error {
+	msg := map[string]any{
+		"text":                     telegramText(ev),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	if ts.cfg.AckButtons && ev.Type == "down" && ev.IncidentID != "" {
+		msg["reply_markup"] = telegramMarkup{InlineKeyboard: [][]telegramButton{{{Text: "✅ Acknowledge", CallbackData: "ack:" + ev.IncidentID}}}}
+	}
+	var failed []string
+	var first error
+	for _, chat := range chats {
+		msg["chat_id"] = chat
+		if err := ts.api.call(ts.client, "sendMessage", msg, nil); err != nil {
+			failed = append(failed, chat)
+			if first == nil {
+				first = fmt.Errorf("chat %s: %v", chat, err)
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &partialError{err: first, failed: map[int]bool{0: true}, retry: func() error { return ts.sendTo(ev, failed) }}
+}
+
+// bots with a running getUpdates loop, keyed by API base + token
+var telegramPollers = struct {
+	sync.Mutex
+	running map[string]bool
+}{running: map[string]bool{}}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Callback *struct {
+		ID   string `json:"id"`
+		Data string `json:"data"`
+		From struct {
+			ID        int64  `json:"id"`
+			Username  string `json:"username"`
+			FirstName string `json:"first_name"`
+		} `json:"from"`
+		Message *struct {
+			MessageID int64 `json:"message_id"`
+			Chat      struct {
+				ID       int64  `json:"id"`
+				Username string `json:"username"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+// startTelegramPoller long-polls button presses for a bot. Several
+// notifiers sharing a bot share one loop; presses are accepted from the
+// chats of the notifier that started it.
+func startTelegramPoller(api *telegramAPI, chats []string) {
+	telegramPollers.Lock()
+	defer telegramPollers.Unlock()
+	key := api.base + "|" + api.token
+	if telegramPollers.running[key] {
+		return
+	}
+	telegramPollers.running[key] = true
+	go pollTelegram(api, chats)
+}
+
+func pollTelegram(api *telegramAPI, chats []string) {
+	client := &http.Client{Timeout: 40 * time.Second}
+	var offset int64
+	var lastErr string
+	for {
+		var updates []telegramUpdate
+		err := api.call(client, "getUpdates", map[string]any{
+			"offset": offset, "timeout": 25, "allowed_updates": []string{"callback_query"},
+		}, &updates)
+		if err != nil {
+			if err.Error() != lastErr {
+				log.Printf("telegram updates: %v", err)
+				lastErr = err.Error()
+			}
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		lastErr = ""
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Callback != nil {
+				handleTelegramCallback(client, api, chats, u)
+			}
+		}
+	}
+}
+
+func handleTelegramCallback(client *http.Client, api *telegramAPI, chats []string, u telegramUpdate) {
+	cb := u.Callback
+	answer := func(text string) {
+		_ = api.call(client, "answerCallbackQuery", map[string]any{"callback_query_id": cb.ID, "text": text}, nil)
+	}
+	id, isAck := strings.CutPrefix(cb.Data, "ack:")
+	if !isAck || cb.Message == nil {
+		answer("Unknown action")
+		return
+	}
+	chat := cb.Message.Chat
+	if !containsFold(chats, strconv.FormatInt(chat.ID, 10)) && (chat.Username == "" || !containsFold(chats, "@"+chat.Username)) {
+		answer("This chat is not allowed to acknowledge alerts")
+		return
+	}
+	user := "telegram:" + cb.From.Username
+	if cb.From.Username == "" {
+		user = fmt.Sprintf("telegram:%s(%d)", cb.From.FirstName, cb.From.ID)
+	}
+	in, ok, err := ackIncident(id, user, "telegram")
+	switch {
+	case err != nil:
+		answer("Cannot acknowledge: " + err.Error())
+		return
+	case !ok:
+		answer("Already acknowledged by " + in.AckedBy)
+	default:
+		answer("Acknowledged")
+		_ = api.call(client, "sendMessage", map[string]any{
+			"chat_id":             chat.ID,
+			"text":                fmt.Sprintf("✅ %s acknowledged the alert for %s", user, in.Service),
+			"reply_to_message_id": cb.Message.MessageID,
+		}, nil)
+	}
+	_ = api.call(client, "editMessageReplyMarkup", map[string]any{
+		"chat_id": chat.ID, "message_id": cb.Message.MessageID, "reply_markup": telegramMarkup{InlineKeyboard: [][]telegramButton{}},
+	}, nil)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WebhookConfig sends each event as an HTTP request. Body is a Go
+// text/template over the Event (default: the event as JSON); Format
+// selects a native chat payload instead. With Secret set, X-SPM-Signature
+// carries "sha256=" + hex HMAC-SHA256 of the body.
+type WebhookConfig struct {
+	URL         string            `json:"url"`
+	Format      string            `json:"format,omitempty"` // slack, mattermost or discord
+	Method      string            `json:"method,omitempty"` // default POST
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	ContentType string            `json:"content_type,omitempty"` // default application/json
+	Secret      string            `json:"secret,omitempty"`
+	Timeout     string            `json:"timeout,omitempty"` // default 10s
+}
+
+type webhookSender struct {
+	cfg    WebhookConfig
+	body   *template.Template
+	client *http.Client
+}
+
+// templateFuncs are available in webhook body templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"duration": formatDurationSec,
+	"join":     strings.Join,
+}
+
+func newWebhookSender(c WebhookConfig) (*webhookSender, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	switch c.Format {
+	case "", "slack", "mattermost", "discord":
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", c.Format)
+	}
+	if c.Format != "" && c.Body != "" {
+		return nil, fmt.Errorf("webhook body and format are mutually exclusive")
+	}
+	for k := range c.Headers {
+		if strings.HasPrefix(strings.ToLower(k), "x-spm-") {
+			return nil, fmt.Errorf("webhook header %s is reserved", k)
+		}
+	}
+	ws := &webhookSender{cfg: c, client: &http.Client{Timeout: parseDurationDefault(c.Timeout, 10*time.Second)}}
+	if c.Body != "" {
+		t, err := template.New("body").Funcs(templateFuncs).Parse(c.Body)
+		if err != nil {
+			return nil, fmt.Errorf("body template: %v", err)
+		}
+		ws.body = t
+	}
+	return ws, nil
+}
+
+func (ws *webhookSender) render(ev Event) ([]byte, error) {
+	switch ws.cfg.Format {
+	case "slack", "mattermost":
+		return slackPayload(ev)
+	case "discord":
+		return discordPayload(ev)
+	}
+	if ws.body == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	if err := ws.body.Execute(&buf, ev); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (ws *webhookSender) send(ev Event) error {
+	body, err := ws.render(ev)
+	if err != nil {
+		return fmt.Errorf("render: %v", err)
+	}
+	return postSigned(ws.client, ws.cfg.Method, ws.cfg.URL, ws.cfg.ContentType, ws.cfg.Headers, ws.cfg.Secret, ev, body)
+}
+
+// postSigned performs a webhook request and treats any non-2xx answer as
+// a failure.
+func postSigned(client *http.Client, method, url, contentType string, headers map[string]string, secret string, ev Event, body []byte) error {
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "sp-monitor/"+version)
+	// custom headers may replace the defaults above but never the
+	// signature and delivery headers below
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("X-SPM-Event", ev.Type)
+	req.Header.Set("X-SPM-Delivery", ev.ID)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-SPM-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PeerConfig is a remote SP Monitor whose status.json is merged into the
+// dashboard. URL is the peer's base URL or the full status.json URL.
+// Token is sent as a Bearer token; Username/Password as basic auth.
+type PeerConfig struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Interval string `json:"interval,omitempty"` // default 30s
+	Timeout  string `json:"timeout,omitempty"`  // default 10s
+}
+
+func (p PeerConfig) statusURL() string {
+	u := strings.TrimRight(p.URL, "/")
+	if strings.HasSuffix(u, ".json") {
+		return u
+	}
+	return u + "/status.json"
+}
+
+// peerState is the last known view of one remote source: a polled peer
+// or a pushing agent.
+type peerState struct {
+	Name      string
+	Kind      string // "peer" or "agent"
+	Host      string // monitor.host reported by the peer
+	Reachable bool
+	Error     string
+	LastOK    time.Time // last successful fetch
+	Interval  time.Duration
+	doc       StatusDocument
+}
+
+// staleAt reports whether the data is too old: the peer says so, its
+// generated_at is past its own limit, or we have not reached it lately.
+func (p *peerState) staleAt(now time.Time) bool {
+	if p.LastOK.IsZero() {
+		return false
+	}
+	if p.doc.Stale || now.Sub(p.LastOK) > staleAfter(p.Interval) {
+		return true
+	}
+	if !p.doc.GeneratedAt.IsZero() && p.doc.IntervalSeconds > 0 {
+		iv := time.Duration(p.doc.IntervalSeconds * float64(time.Second))
+		return now.Sub(p.doc.GeneratedAt) > staleAfter(iv)+p.Interval
+	}
+	return false
+}
+
+// remote sources (federated peers) keyed by name
+var peers = struct {
+	sync.RWMutex
+	order []string
+	state map[string]*peerState
+}{state: map[string]*peerState{}}
+
+// peerRegister adds a source in configuration order before its first update.
+func peerRegister(name, kind string, interval time.Duration) {
+	peers.Lock()
+	defer peers.Unlock()
+	if _, ok := peers.state[name]; ok {
+		return
+	}
+	msg := "not fetched yet"
+	if kind == "agent" {
+		msg = "no push yet"
+	}
+	peers.state[name] = &peerState{Name: name, Kind: kind, Interval: interval, Error: msg}
+	peers.order = append(peers.order, name)
+}
+
+// peerUpdate records a fetch result; a failed fetch keeps the last data.
+func peerUpdate(name string, doc *StatusDocument, err error) {
+	peers.Lock()
+	defer peers.Unlock()
+	st, ok := peers.state[name]
+	if !ok {
+		return
+	}
+	if err != nil {
+		if st.Error != err.Error() {
+			log.Printf("peer %s unreachable: %v", name, err)
+		}
+		st.Reachable = false
+		st.Error = err.Error()
+		return
+	}
+	if !st.Reachable && !st.LastOK.IsZero() {
+		log.Printf("peer %s reachable again", name)
+	}
+	st.Reachable = true
+	st.Error = ""
+	st.LastOK = time.Now()
+	st.doc = *doc
+	st.Host = doc.Monitor.Host
+	if st.Kind == "agent" && doc.IntervalSeconds > 0 {
+		// agents push once per check cycle
+		st.Interval = time.Duration(doc.IntervalSeconds * float64(time.Second))
+	}
+}
+
+func fetchPeer(client *http.Client, p PeerConfig) (*StatusDocument, error) {
+	req, err := http.NewRequest(http.MethodGet, p.statusURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	} else if p.Username != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseStatusDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad status.json: %v", err)
+	}
+	if doc.Stale || resp.Header.Get("X-Status-Stale") == "true" {
+		doc.Stale = true
+	}
+	return &doc, nil
+}
+
+// startPeers polls every configured peer in its own goroutine.
+func startPeers(list []PeerConfig) {
+	for _, p := range list {
+		if p.Name == "" || p.URL == "" {
+			log.Printf("peer ignored: name and url are required (name=%q)", p.Name)
+			continue
+		}
+		interval := parseDurationDefault(p.Interval, 30*time.Second)
+		client := &http.Client{Timeout: parseDurationDefault(p.Timeout, 10*time.Second)}
+		peerRegister(p.Name, "peer", interval)
+		go func(p PeerConfig) {
+			for {
+				doc, err := fetchPeer(client, p)
+				peerUpdate(p.Name, doc, err)
+				time.Sleep(interval)
+			}
+		}(p)
+	}
+}
+
+// peerView is a remote group on the dashboard.
+type peerView struct {
+	Name        string
+	Kind        string
+	Host        string
+	Reachable   bool
+	Stale       bool
+	Error       string
+	LastOK      time.Time
+	GeneratedAt time.Time
+	Services    []serviceView
+}
+
+// Status is the peer's own state: up, stale or down (unreachable).
+func (p peerView) Status() string {
+	switch {
+	case !p.Reachable:
+		return "down"
+	case p.Stale:
+		return "stale"
+	default:
+		return "up"
+	}
+}
+
+// peerViews snapshots all remote sources for rendering. Peer cards never
+// get controls (actions are only available on the peer itself); agent
+// cards keep theirs, requests are relayed to the agent.
+func peerViews(now time.Time) []peerView {
+	peers.RLock()
+	defer peers.RUnlock()
+	res := make([]peerView, 0, len(peers.order))
+	for _, name := range peers.order {
+		st := peers.state[name]
+		v := peerView{Name: st.Name, Kind: st.Kind, Host: st.Host, Reachable: st.Reachable, Stale: st.staleAt(now), Error: st.Error, LastOK: st.LastOK, GeneratedAt: st.doc.GeneratedAt}
+		services := sortServices(st.doc.services())
+		for _, s := range services {
+			sv := serviceView{Service: s}
+			if st.Kind == "agent" {
+				sv.Agent = st.Name
+			} else {
+				sv.Controls, sv.ControlsRun, sv.ControlsShut = false, false, false
+			}
+			v.Services = append(v.Services, sv)
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// handlePeers serves GET /api/peers: reachability of every peer and agent.
+// Fetch errors (they name internal URLs) are only shown when logged in.
+func handlePeers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	type peerJSON struct {
+		Name        string    `json:"name"`
+		Kind        string    `json:"kind"`
+		Host        string    `json:"host,omitempty"`
+		Status      string    `json:"status"` // up, stale or down
+		Error       string    `json:"error,omitempty"`
+		LastOK      time.Time `json:"last_ok,omitzero"`
+		GeneratedAt time.Time `json:"generated_at,omitzero"`
+		Services    int       `json:"services"`
+	}
+	anon := authUser(r) == ""
+	out := []peerJSON{}
+	for _, v := range peerViews(time.Now()) {
+		pj := peerJSON{Name: v.Name, Kind: v.Kind, Host: v.Host, Status: v.Status(), Error: v.Error, LastOK: v.LastOK, GeneratedAt: v.GeneratedAt, Services: len(v.Services)}
+		if anon {
+			pj.Error = ""
+		}
+		out = append(out, pj)
+	}
+	respondJSON(w, map[string]any{"peers": out})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pingStats summarises a series of echo probes.
+type pingStats struct {
+	Sent int
+	Recv int
+	RTTs []time.Duration
+}
+
+func (p pingStats) loss() float64 {
+	if p.Sent == 0 {
+		return 100
+	}
+	return float64(p.Sent-p.Recv) * 100 / float64(p.Sent)
+}
+
+func (p pingStats) avg() time.Duration {
+	if len(p.RTTs) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range p.RTTs {
+		sum += d
+	}
+	return sum / time.Duration(len(p.RTTs))
+}
+
+func checkPing(pc *PingCheck) checkResult {
+	if pc == nil || pc.Host == "" {
+		return checkResult{Reason: "ping check has no host"}
+	}
+	count := pc.Count
+	if count <= 0 {
+		count = 3
+	}
+	interval := parseDurationDefault(pc.Interval, 200*time.Millisecond)
+	timeout := parseDurationDefault(pc.Timeout, time.Second)
+	maxLoss := 100.0
+	if pc.MaxLoss != nil {
+		maxLoss = *pc.MaxLoss
+	}
+
+	st, err := icmpPing(pc.Host, count, interval, timeout)
+	if err != nil {
+		return checkResult{Reason: err.Error()}
+	}
+	loss := st.loss()
+	res := checkResult{Latency: st.avg(), Detail: fmt.Sprintf("loss %.0f%%", loss)}
+	if st.Recv > 0 {
+		res.Detail += fmt.Sprintf(", rtt %.1f ms", float64(st.avg().Microseconds())/1000)
+	}
+	switch {
+	case st.Recv == 0:
+		res.Reason = fmt.Sprintf("no reply from %s (%d probes)", pc.Host, st.Sent)
+	case loss > maxLoss:
+		res.Reason = fmt.Sprintf("packet loss %.0f%% exceeds %.0f%%", loss, maxLoss)
+	default:
+		res.Active = true
+	}
+	return res
+}
+
+var pingTimeRe = regexp.MustCompile(`[=<]\s*([0-9]+(?:[.,][0-9]+)?)\s*ms`)
+
+// pingViaCommand falls back to the system ping binary, one probe per exec.
+func pingViaCommand(host string, count int, interval, timeout time.Duration) (pingStats, error) {
+	var st pingStats
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "windows":
+			cmd = exec.Command("ping", "-n", "1", "-w", strconv.FormatInt(timeout.Milliseconds(), 10), host)
+		case "linux":
+			cmd = exec.Command("ping", "-c", "1", "-W", strconv.Itoa(int((timeout+time.Second-1)/time.Second)), host)
+		default:
+			cmd = exec.Command("ping", "-c", "1", host)
+		}
+		st.Sent++
+		out, err := cmd.Output()
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				return st, fmt.Errorf("ping: %v", err)
+			}
+			continue
+		}
+		m := pingTimeRe.FindStringSubmatch(string(out))
+		if m == nil {
+			continue
+		}
+		ms, _ := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+		st.Recv++
+		st.RTTs = append(st.RTTs, time.Duration(ms*float64(time.Millisecond)))
+	}
+	return st, nil
+}
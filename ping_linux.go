@@ -0,0 +1,115 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var icmpFallbackOnce sync.Once
+
+// icmpPing sends echo requests over an unprivileged ICMP datagram socket
+// (net.ipv4.ping_group_range). If the kernel refuses it, a raw socket is
+// tried (root/CAP_NET_RAW), then the system ping binary.
+func icmpPing(host string, count int, interval, timeout time.Duration) (pingStats, error) {
+	ipAddr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return pingStats{}, fmt.Errorf("resolve %s: %v", host, err)
+	}
+	v4 := ipAddr.IP.To4() != nil
+	var dst net.Addr = &net.UDPAddr{IP: ipAddr.IP, Zone: ipAddr.Zone}
+	raw := false
+	conn, err := openICMPDatagram(v4)
+	if err != nil {
+		network := "ip4:icmp"
+		if !v4 {
+			network = "ip6:ipv6-icmp"
+		}
+		var rawErr error
+		conn, rawErr = net.ListenPacket(network, "")
+		if rawErr != nil {
+			icmpFallbackOnce.Do(func() {
+				log.Printf("icmp sockets unavailable (%v; raw: %v), using ping binary", err, rawErr)
+			})
+			return pingViaCommand(host, count, interval, timeout)
+		}
+		dst, raw = ipAddr, true
+	}
+	defer conn.Close()
+
+	reqType, replyType := byte(8), byte(0)
+	if !v4 {
+		reqType, replyType = 128, 129
+	}
+	// identifier is rewritten by the kernel for datagram sockets; raw sockets
+	// see every echo reply on the host, so match on it there
+	id := uint16(os.Getpid())
+	var st pingStats
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			time.Sleep(interval)
+		}
+		msg := []byte{reqType, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq), 's', 'p', 'm', 'o', 'n'}
+		if v4 {
+			binary.BigEndian.PutUint16(msg[2:], icmpChecksum(msg))
+		}
+		sent := time.Now()
+		st.Sent++
+		if _, err := conn.WriteTo(msg, dst); err != nil {
+			continue
+		}
+		_ = conn.SetReadDeadline(sent.Add(timeout))
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				break // timeout: probe lost
+			}
+			if n < 8 || buf[0] != replyType || int(binary.BigEndian.Uint16(buf[6:])) != seq {
+				continue
+			}
+			if raw && binary.BigEndian.Uint16(buf[4:]) != id {
+				continue
+			}
+			st.Recv++
+			st.RTTs = append(st.RTTs, time.Since(sent))
+			break
+		}
+	}
+	return st, nil
+}
+
+func openICMPDatagram(v4 bool) (net.PacketConn, error) {
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	if !v4 {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// icmpPing uses the system ping binary where unprivileged ICMP sockets are unavailable.
+func icmpPing(host string, count int, interval, timeout time.Duration) (pingStats, error) {
+	return pingViaCommand(host, count, interval, timeout)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SLARow is the availability summary of one service over a report range.
+type SLARow struct {
+	Service       string   `json:"service"`
+	Availability  *float64 `json:"availability,omitempty"` // percent; nil without samples
+	Samples       int      `json:"samples"`
+	Incidents     int      `json:"incidents"`
+	DowntimeSec   int64    `json:"downtime_seconds"`
+	MTTRSec       int64    `json:"mttr_seconds"`
+	LongestOutage int64    `json:"longest_outage_seconds"`
+}
+
+// SLAReport covers [From, To). Maintenance windows are excluded from
+// availability and downtime unless IncludeMaintenance is set.
+type SLAReport struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	GeneratedAt        time.Time `json:"generated_at"`
+	IncludeMaintenance bool      `json:"include_maintenance"`
+	Services           []SLARow  `json:"services"`
+}
+
+// buildSLAReport combines stored history (availability) with incidents
+// (count, MTTR, longest outage). Outage durations are clipped to the range;
+// MTTR only counts outages resolved within it.
+func buildSLAReport(infos []ServiceInfo, from, to time.Time, includeMaint bool) SLAReport {
+	rep := SLAReport{From: from, To: to, GeneratedAt: time.Now(), IncludeMaintenance: includeMaint}
+	for _, si := range infos {
+		row := SLARow{Service: si.Name}
+		avail, total := statusHistory.counts(si.Name, from, to, includeMaint)
+		row.Samples = total
+		if total > 0 {
+			pct := float64(avail) * 100 / float64(total)
+			row.Availability = &pct
+		}
+		var resolved, resolvedSum int64
+		for _, o := range slaOutages(si.Name, from, to, rep.GeneratedAt, includeMaint) {
+			row.Incidents++
+			row.DowntimeSec += o.clipped
+			if o.clipped > row.LongestOutage {
+				row.LongestOutage = o.clipped
+			}
+			if !o.open && !o.end.After(to) {
+				resolved++
+				resolvedSum += o.down
+			}
+		}
+		if resolved > 0 {
+			row.MTTRSec = resolvedSum / resolved
+		}
+		rep.Services = append(rep.Services, row)
+	}
+	return rep
+}
+
+// slaOutage is one outage in a report. The incident store closes an
+// incident when maintenance starts and opens a new one if the service is
+// still down when it ends; such incidents are joined into one outage.
+type slaOutage struct {
+	end     time.Time
+	open    bool
+	down    int64 // seconds, the whole outage
+	clipped int64 // seconds within the report range
+}
+
+// slaOutages joins the incidents of a service that only maintenance
+// separates (history shows no up sample in between). With includeMaint the
+// time in between counts as downtime in proportion to the failed probes.
+func slaOutages(name string, from, to, now time.Time, includeMaint bool) []slaOutage {
+	clip := func(start, end time.Time) int64 {
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			return 0
+		}
+		return int64(end.Sub(start).Seconds())
+	}
+	list := incidents.list(name, from, to, 0)
+	var res []slaOutage
+	for i := len(list) - 1; i >= 0; i-- { // oldest first
+		in := list[i]
+		end := now
+		if in.End != nil {
+			end = *in.End
+		}
+		if n := len(res); n > 0 {
+			prev := &res[n-1]
+			up, outside := statusHistory.counts(name, prev.end, in.Start, false)
+			upAll, all := statusHistory.counts(name, prev.end, in.Start, true)
+			if maint := all - outside; up == 0 && maint > 0 {
+				if includeMaint {
+					failed := float64(maint-(upAll-up)) / float64(maint)
+					prev.down += int64(failed * in.Start.Sub(prev.end).Seconds())
+					prev.clipped += int64(failed * float64(clip(prev.end, in.Start)))
+				}
+				prev.end, prev.open = end, in.open()
+				prev.down += in.Duration
+				prev.clipped += clip(in.Start, end)
+				continue
+			}
+		}
+		res = append(res, slaOutage{end: end, open: in.open(), down: in.Duration, clipped: clip(in.Start, end)})
+	}
+	return res
+}
+
+// reportRange resolves month (YYYY-MM) or from/to; default is the previous
+// calendar month. Dates are local, `to` is exclusive.
+func reportRange(month, fromStr, toStr string) (time.Time, time.Time, error) {
+	if month != "" {
+		m, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("bad month %q (want YYYY-MM)", month)
+		}
+		return m, m.AddDate(0, 1, 0), nil
+	}
+	if fromStr == "" && toStr == "" {
+		now := time.Now()
+		cur := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		return cur.AddDate(0, -1, 0), cur, nil
+	}
+	from, err := parseTimeParam(fromStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("bad from %q", fromStr)
+	}
+	to, err := parseTimeParam(toStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("bad to %q", toStr)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("empty range")
+	}
+	return from, to, nil
+}
+
+func fmtAvailability(p *float64) string {
+	if p == nil {
+		return "n/a"
+	}
+	return strconv.FormatFloat(*p, 'f', 3, 64)
+}
+
+func writeReportCSV(w io.Writer, rep SLAReport) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"service", "availability_pct", "samples", "incidents", "downtime_seconds", "mttr_seconds", "longest_outage_seconds"})
+	for _, r := range rep.Services {
+		_ = cw.Write([]string{r.Service, fmtAvailability(r.Availability), strconv.Itoa(r.Samples), strconv.Itoa(r.Incidents),
+			strconv.FormatInt(r.DowntimeSec, 10), strconv.FormatInt(r.MTTRSec, 10), strconv.FormatInt(r.LongestOutage, 10)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportJSON(w io.Writer, rep SLAReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+func writeReportHTML(w io.Writer, rep SLAReport, templatePath string) error {
+	tmpl, err := template.New("report.html").Funcs(template.FuncMap{
+		"availability": fmtAvailability,
+		"duration":     formatDurationSec,
+		"deref": func(p *float64) float64 {
+			if p == nil {
+				return 0
+			}
+			return *p
+		},
+	}).ParseFiles(templatePath)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "report.html", rep); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// formatDurationSec renders seconds as "3h 12m" / "45s".
+func formatDurationSec(sec int64) string {
+	d := time.Duration(sec) * time.Second
+	switch {
+	case sec == 0:
+		return "—"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", sec)
+	case d < time.Hour:
+		return fmt.Sprintf("%dm %ds", sec/60, sec%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", sec/3600, sec%3600/60)
+	default:
+		return fmt.Sprintf("%dd %dh", sec/86400, sec%86400/3600)
+	}
+}
+
+func writeReport(w io.Writer, rep SLAReport, format, templatePath string) error {
+	switch format {
+	case "csv":
+		return writeReportCSV(w, rep)
+	case "json":
+		return writeReportJSON(w, rep)
+	case "html":
+		return writeReportHTML(w, rep, templatePath)
+	default:
+		return fmt.Errorf("unknown format %q (csv, json, html)", format)
+	}
+}
+
+// runReportCommand implements `port-monitor report`.
+func runReportCommand(args []string, infos []ServiceInfo, templatePath string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	month := fs.String("month", "", "month to report (YYYY-MM); default previous month")
+	from := fs.String("from", "", "range start (YYYY-MM-DD or RFC3339)")
+	to := fs.String("to", "", "range end, exclusive (YYYY-MM-DD or RFC3339); default now")
+	format := fs.String("format", "csv", "output format: csv, json or html")
+	out := fs.String("o", "", "output file (default stdout)")
+	inclMaint := fs.Bool("include-maintenance", false, "count maintenance windows in availability")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	f, t, err := reportRange(*month, *from, *to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "report:", err)
+		return 2
+	}
+	rep := buildSLAReport(infos, f, t, *inclMaint)
+	var buf bytes.Buffer
+	if err := writeReport(&buf, rep, *format, templatePath); err != nil {
+		fmt.Fprintln(os.Stderr, "report:", err)
+		return 1
+	}
+	if *out == "" {
+		_, _ = os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := osWriteAtomic(*out, buf.Bytes()); err != nil {
+		fmt.Fprintln(os.Stderr, "report:", err)
+		return 1
+	}
+	return 0
+}
+
+// reportHandler serves GET /api/report?month=|from=&to=&format=&include_maintenance=1
+// (auth required).
+func reportHandler(infos []ServiceInfo, templatePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if authUser(r) == "" {
+			respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		q := r.URL.Query()
+		from, to, err := reportRange(q.Get("month"), q.Get("from"), q.Get("to"))
+		if err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		format := q.Get("format")
+		if format == "" {
+			format = "json"
+		}
+		rep := buildSLAReport(infos, from, to, q.Get("include_maintenance") == "1")
+		var buf bytes.Buffer
+		if err := writeReport(&buf, rep, format, templatePath); err != nil {
+			respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		name := fmt.Sprintf("sla-%s-%s", from.Format("20060102"), to.Format("20060102"))
+		switch format {
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename="+name+".csv")
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+		case "html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		_, _ = w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RouteRule sends events of matching services to the Notify notifiers.
+// A service matches by name (Services), tag (Tags) or a regexp on its
+// name (Match); with none of them every service matches. Severity limits
+// the rule to some severities (empty = all). Rules are checked in order
+// and all matching ones apply until one with Stop matches.
+type RouteRule struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Match    string   `json:"match,omitempty"`
+	Severity []string `json:"severity,omitempty"` // critical, major, minor, info
+	Notify   []string `json:"notify"`
+	Stop     bool     `json:"stop,omitempty"`
+}
+
+var severities = []string{"critical", "major", "minor", "info"}
+
+type routeRule struct {
+	RouteRule
+	re *regexp.Regexp
+}
+
+func (r *routeRule) matches(ev Event, severity string) bool {
+	if len(r.Severity) > 0 && !containsFold(r.Severity, severity) {
+		return false
+	}
+	if len(r.Services) == 0 && len(r.Tags) == 0 && r.re == nil {
+		return true
+	}
+	return containsFold(r.Services, ev.Service) || hasAnyTag(ev.Tags, r.Tags) || (r.re != nil && r.re.MatchString(ev.Service))
+}
+
+// routing rules; notifiers named in any rule only get routed events,
+// the others keep receiving everything their own filters allow.
+var routing struct {
+	rules  []*routeRule
+	routed map[string]bool
+}
+
+// setupRouting compiles the rules; invalid ones are skipped with a log
+// line, unknown notifier names are only reported.
+func setupRouting(rules []RouteRule, notifiers []NotifierConfig) {
+	routing.routed = map[string]bool{}
+	known := map[string]bool{}
+	for _, n := range notifiers {
+		known[n.Name] = true
+	}
+	for _, rr := range rules {
+		r := &routeRule{RouteRule: rr}
+		if rr.Match != "" {
+			re, err := regexp.Compile(rr.Match)
+			if err != nil {
+				log.Printf("route %s ignored: bad match: %v", rr.Name, err)
+				continue
+			}
+			r.re = re
+		}
+		bad := ""
+		for _, s := range rr.Severity {
+			if !containsFold(severities, s) {
+				bad = s
+			}
+		}
+		if bad != "" {
+			log.Printf("route %s ignored: unknown severity %q", rr.Name, bad)
+			continue
+		}
+		for _, name := range rr.Notify {
+			if !known[name] {
+				log.Printf("route %s: unknown notifier %q", rr.Name, name)
+			}
+			routing.routed[name] = true
+		}
+		routing.rules = append(routing.rules, r)
+	}
+}
+
+// eventSeverity ranks an event for routing: a down is major (critical for
+// critical services and escalations), degraded is minor. Recoveries and
+// acknowledgements take the severity of the outage they end, so they
+// reach the same people; everything else is info.
+func eventSeverity(ev Event) string {
+	state := ""
+	switch ev.Type {
+	case "down", "degraded":
+		state = ev.Type
+	case "up":
+		state = ev.PrevState
+	case "ack":
+		state = "down"
+	}
+	switch {
+	case state == "down" && (ev.Critical || ev.Escalated):
+		return "critical"
+	case state == "down":
+		return "major"
+	case state == "degraded":
+		return "minor"
+	}
+	return "info"
+}
+
+// routeResult is where the rules send one event.
+type routeResult struct {
+	Severity string          `json:"severity"`
+	Rules    []string        `json:"rules"`
+	Targets  map[string]bool `json:"-"`
+}
+
+func routeEvent(ev Event) routeResult {
+	res := routeResult{Severity: eventSeverity(ev), Rules: []string{}, Targets: map[string]bool{}}
+	for _, r := range routing.rules {
+		if !r.matches(ev, res.Severity) {
+			continue
+		}
+		res.Rules = append(res.Rules, r.Name)
+		for _, name := range r.Notify {
+			res.Targets[name] = true
+		}
+		if r.Stop {
+			break
+		}
+	}
+	return res
+}
+
+// rejects explains why n does not get ev ("" = it does), given the
+// notifiers the routing rules selected.
+func (n *notifier) rejects(ev Event, targets map[string]bool) string {
+	if why := n.cfg.rejects(ev); why != "" {
+		return why
+	}
+	if routing.routed[n.cfg.Name] && !targets[n.cfg.Name] {
+		return "no routing rule sends this event here"
+	}
+	return ""
+}
+
+func (n *notifier) accepts(ev Event, targets map[string]bool) bool {
+	return n.rejects(ev, targets) == ""
+}
+
+// handleRoutes serves GET /api/routes (the rules) and POST
+// /api/routes/test, a dry run showing which notifiers an event would
+// reach. Both require a login.
+func handleRoutes(w http.ResponseWriter, r *http.Request) {
+	if authUser(r) == "" {
+		respondJSONCode(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/api/routes":
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rules := []RouteRule{}
+		for _, rr := range routing.rules {
+			rules = append(rules, rr.RouteRule)
+		}
+		respondJSON(w, map[string]any{"rules": rules})
+	case "/api/routes/test":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleRouteTest(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// routeTestRequest describes the event to try. Tags and Critical default
+// to the service's configuration in services.json.
+type routeTestRequest struct {
+	Service   string   `json:"service"`
+	Type      string   `json:"type"` // default down
+	PrevState string   `json:"prev_state,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Critical  *bool    `json:"critical,omitempty"`
+	Escalated bool     `json:"escalated,omitempty"`
+}
+
+func handleRouteTest(w http.ResponseWriter, r *http.Request) {
+	var req routeTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Service == "" {
+		respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": "service is required"})
+		return
+	}
+	if req.Type == "" {
+		req.Type = "down"
+	}
+	switch req.Type {
+	case "down", "degraded", "up", "action", "ack":
+	default:
+		respondJSONCode(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown type %q", req.Type)})
+		return
+	}
+	now := time.Now()
+	ev := prepareEvent(Event{Type: req.Type, Service: req.Service, PrevState: req.PrevState, Time: now, Escalated: req.Escalated})
+	ev.State = req.Type
+	if req.Type == "up" && ev.PrevState == "" {
+		ev.PrevState = "down"
+	}
+	ev.Tags = notifyServiceInfo(req.Service).Tags
+	if req.Tags != nil {
+		ev.Tags = req.Tags
+	}
+	if req.Critical != nil {
+		ev.Critical = *req.Critical
+	}
+	res := routeEvent(ev)
+	type targetJSON struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Deliver bool   `json:"deliver"`
+		Held    bool   `json:"held,omitempty"` // quiet hours: goes out with the digest
+		Reason  string `json:"reason,omitempty"`
+	}
+	out := []targetJSON{}
+	notifications.RLock()
+	for _, n := range notifications.list {
+		t := targetJSON{Name: n.cfg.Name, Type: n.cfg.Type, Reason: n.rejects(ev, res.Targets)}
+		t.Deliver = t.Reason == ""
+		t.Held = t.Deliver && n.quietAt(ev, now)
+		out = append(out, t)
+	}
+	notifications.RUnlock()
+	respondJSON(w, map[string]any{"event": ev, "severity": res.Severity, "rules": res.Rules, "notifiers": out})
+}
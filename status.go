@@ -14,6 +14,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,49 +30,143 @@ func osWriteAtomic(path string, data []byte) error {
 	return os.Rename(TMP, path)
 }
 
-// exportStatusFile writes current service statuses to JSON atomically
-func exportStatusFile(services []ServiceInfo, path string) error {
-	status := getServicesStatus(services)
-	data, err := json.MarshalIndent(status, "", "  ")
+// runStatusCycle checks all services, logs transitions, records history
+// and exports the result. Called from the background loop only.
+func runStatusCycle(services []ServiceInfo, path string) error {
+	now := time.Now()
+	curr := getServicesStatus(services)
+	maintenance.apply(curr, now)
+	detectAndLogStatusChanges(lastStatus, curr)
+	statusHistory.record(curr, now)
+	statusHistory.decorate(curr, now)
+	metricsObserveCycle(curr, time.Since(now))
+	queueAgentPush(curr)
+	return exportStatusFile(curr, path)
+}
+
+// refreshStatusFile re-checks and exports without touching change
+// detection or history (used right after start/stop actions).
+func refreshStatusFile(services []ServiceInfo, path string) error {
+	now := time.Now()
+	curr := getServicesStatus(services)
+	maintenance.apply(curr, now)
+	stampStatusTimes(curr, now)
+	statusHistory.decorate(curr, now)
+	return exportStatusFile(curr, path)
+}
+
+// exportStatusFile writes service statuses to JSON atomically, as a
+// StatusDocument or, with EXPORT_FORMAT=legacy, as the bare array.
+func exportStatusFile(status []Service, path string) error {
+	var v any = newStatusDocument(status, time.Now())
+	if envCfg.ExportFormat == "legacy" {
+		v = status
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
 	return osWriteAtomic(path, data)
 }
 
+// loadStatusFile reads either export format.
 func loadStatusFile(path string) ([]Service, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	var services []Service
-	if err := json.Unmarshal(data, &services); err != nil {
+	doc, err := parseStatusDocument(data)
+	if err != nil {
 		return nil, err
 	}
-	return services, nil
+	return doc.services(), nil
 }
 
+// getServicesStatus checks services with up to envCfg.CheckWorkers running
+// at once. Checks still running after envCfg.CheckDeadline are reported
+// down; they finish in the background and their result is dropped.
 func getServicesStatus(services []ServiceInfo) []Service {
-	var result []Service
-	for _, s := range services {
-		var active, isSystemd bool
-		unit := s.ServiceName
-		if unit == "" {
-			unit = s.SystemdName
+	type checked struct {
+		i int
+		s Service
+	}
+	jobs := make(chan int, len(services))
+	for i := range services {
+		jobs <- i
+	}
+	close(jobs)
+	results := make(chan checked, len(services))
+	stop := make(chan struct{})
+	for range min(max(envCfg.CheckWorkers, 1), len(services)) {
+		go func() {
+			for i := range jobs {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				results <- checked{i, checkService(services[i])}
+			}
+		}()
+	}
+
+	result := make([]Service, len(services))
+	done := make([]bool, len(services))
+	deadline := time.NewTimer(envCfg.CheckDeadline)
+	defer deadline.Stop()
+wait:
+	for n := 0; n < len(services); n++ {
+		select {
+		case c := <-results:
+			result[c.i], done[c.i] = c.s, true
+		case <-deadline.C:
+			break wait
 		}
-		if runtime.GOOS == "linux" && unit != "" {
-			active = isSystemdServiceActive(unit)
-			isSystemd = true
-		} else if runtime.GOOS == "windows" && unit != "" {
-			active = isWindowsServiceActive(unit) || isWindowsProcessActive(unit)
-		} else if s.Port > 0 {
-			active = isPortInUse(s.Port)
+	}
+	close(stop)
+	for i, s := range services {
+		if !done[i] {
+			result[i] = newService(s)
+			result[i].Reason = fmt.Sprintf("check did not finish within %s", envCfg.CheckDeadline)
+			log.Printf("check %s timed out after %s", s.Name, envCfg.CheckDeadline)
 		}
-		result = append(result, Service{Port: s.Port, Name: s.Name, Link: s.Link, Image: s.Image, ShowPort: s.ShowPort, SystemdName: unit, IsSystemd: isSystemd, Active: active, Controls: s.Controls, ControlsRun: s.ControlsRun, ControlsShut: s.ControlsShut})
 	}
 	return result
 }
 
+// newService is the down Service for s before any probe ran.
+func newService(s ServiceInfo) Service {
+	unit := s.ServiceName
+	if unit == "" {
+		unit = s.SystemdName
+	}
+	return Service{Port: s.Port, Name: s.Name, Link: s.Link, Image: s.Image, ShowPort: s.ShowPort, SystemdName: unit, State: serviceState(false, false), Controls: s.Controls, ControlsRun: s.ControlsRun, ControlsShut: s.ControlsShut, Type: s.Type, Tags: s.Tags}
+}
+
+// checkService runs the typed check of s, or probes its systemd unit,
+// Windows service or port.
+func checkService(s ServiceInfo) Service {
+	sv := newService(s)
+	var degraded bool
+	unit := sv.SystemdName
+	if res, ok := runTypedCheck(s); ok {
+		sv.Active = res.Active
+		degraded = res.Degraded
+		sv.Reason = res.Reason
+		sv.Detail = res.Detail
+		sv.LatencyMs = float64(res.Latency.Microseconds()) / 1000
+	} else if runtime.GOOS == "linux" && unit != "" {
+		sv.Active = isSystemdServiceActive(unit)
+		sv.IsSystemd = true
+	} else if runtime.GOOS == "windows" && unit != "" {
+		sv.Active = isWindowsServiceActive(unit) || isWindowsProcessActive(unit)
+	} else if s.Port > 0 {
+		sv.Active = isPortInUse(s.Port)
+	}
+	sv.State = serviceState(sv.Active, degraded)
+	return sv
+}
+
 func isPortInUse(port int) bool {
 	if port <= 0 {
 		return false
@@ -104,8 +199,24 @@ func isSystemdServiceActive(name string) bool {
 	return exec.Command("systemctl", "is-active", "--quiet", name).Run() == nil
 }
 
-// renderHTML builds page
-func renderHTML(w http.ResponseWriter, services []Service, templatePath string) {
+// serviceView is a card in the page model: the Service plus its recent
+// history timeline (empty when no local history exists).
+type serviceView struct {
+	Service
+	Timeline []timelineSlot
+	Agent    string // owning agent for relayed controls, empty for local
+}
+
+// pageData is the template model for index.html.
+type pageData struct {
+	Services []serviceView
+	Banner   string     // stale/missing data notice, empty when fresh
+	Host     string     // local monitor host, titles the local group
+	Peers    []peerView // federated peers, grouped below the local cards
+}
+
+// sortServices puts active services first, each part sorted by name.
+func sortServices(services []Service) []Service {
 	var active, inactive []Service
 	for _, s := range services {
 		if s.Active {
@@ -116,7 +227,17 @@ func renderHTML(w http.ResponseWriter, services []Service, templatePath string)
 	}
 	sort.Slice(active, func(i, j int) bool { return active[i].Name < active[j].Name })
 	sort.Slice(inactive, func(i, j int) bool { return inactive[i].Name < inactive[j].Name })
-	services = append(active, inactive...)
+	return append(active, inactive...)
+}
+
+// renderHTML builds page
+func renderHTML(w http.ResponseWriter, services []Service, banner, templatePath string) {
+	services = sortServices(services)
+	now := time.Now()
+	page := pageData{Services: make([]serviceView, 0, len(services)), Banner: banner, Host: monitorHost, Peers: peerViews(now)}
+	for _, s := range services {
+		page.Services = append(page.Services, serviceView{Service: s, Timeline: statusHistory.timeline(s.Name, now)})
+	}
 	tmpl, err := template.New("index.html").Funcs(template.FuncMap{
 		"getInitials": func(name string) string {
 			for _, c := range name {
@@ -126,7 +247,9 @@ func renderHTML(w http.ResponseWriter, services []Service, templatePath string)
 			}
 			return "?"
 		},
-		"Year": func() int { return time.Now().Year() },
+		"Year":       func() int { return time.Now().Year() },
+		"ago":        func(t time.Time) string { return formatDurationSec(int64(time.Since(t).Seconds())) },
+		"uptimeList": uptimeList,
 	}).ParseFiles(templatePath)
 	if err != nil {
 		log.Printf("template parse error: %v (template=%s)", err, templatePath)
@@ -134,7 +257,14 @@ func renderHTML(w http.ResponseWriter, services []Service, templatePath string)
 		return
 	}
 	var buf bytes.Buffer
-	if err := tmpl.ExecuteTemplate(&buf, "index.html", services); err != nil {
+	if err := tmpl.ExecuteTemplate(&buf, "index.html", page); err != nil {
+		// custom templates written for the old model range over []Service
+		buf.Reset()
+		if tmpl.ExecuteTemplate(&buf, "index.html", services) == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(buf.Bytes())
+			return
+		}
 		log.Printf("template exec error: %v (template=%s)", err, templatePath)
 		http.Error(w, fmt.Sprintf("template exec error: %v", err), http.StatusInternalServerError)
 		return
@@ -144,27 +274,54 @@ func renderHTML(w http.ResponseWriter, services []Service, templatePath string)
 }
 
 // track last exported state to detect status changes
-var lastStatus = map[string]bool{} // key: name|port|systemd
+var lastStatus = map[string]string{} // key: name|port|systemd -> state
+
+// when each service last changed state (read by HTTP handlers)
+var lastChanged = struct {
+	sync.RWMutex
+	at map[string]time.Time // key: name|port|systemd
+}{at: map[string]time.Time{}}
 
-func detectAndLogStatusChanges(prev map[string]bool, curr []Service) {
+func statusKey(s Service) string {
+	return fmt.Sprintf("%s|%d|%s", s.Name, s.Port, s.SystemdName)
+}
+
+// detectAndLogStatusChanges logs transitions, feeds incidents and
+// notifiers and stamps LastChecked/LastChanged on curr.
+func detectAndLogStatusChanges(prev map[string]string, curr []Service) {
 	now := time.Now()
-	for _, s := range curr {
-		key := fmt.Sprintf("%s|%d|%s", s.Name, s.Port, s.SystemdName)
+	lastChanged.Lock()
+	defer lastChanged.Unlock()
+	for i := range curr {
+		s := &curr[i]
+		key := statusKey(*s)
+		s.LastChecked = now
 		old, ok := prev[key]
 		if !ok {
-			prev[key] = s.Active
+			// LastChanged stays unset until a transition is seen
+			prev[key] = s.State
+			notifyStatusChange(*s, "", incidents.observe(*s, now), now)
 			continue
 		}
-		if old != s.Active {
-			prev[key] = s.Active
+		if old != s.State {
+			prev[key] = s.State
+			lastChanged.at[key] = now
+			notifyStatusChange(*s, old, incidents.observe(*s, now), now)
 			// pseudo ServiceInfo for logging
 			si := ServiceInfo{Port: s.Port, Name: s.Name, ServiceName: s.SystemdName, SystemdName: s.SystemdName}
-			state := "up"
-			if !s.Active {
-				state = "down"
-			}
-			_ = logAction(appCfg.LogFile, now, "monitor", "127.0.0.1", &si, "status", state)
+			_ = logAction(appCfg.LogFile, now, "monitor", "127.0.0.1", &si, "status", s.State)
 		}
+		s.LastChanged = lastChanged.at[key]
+	}
+}
+
+// stampStatusTimes fills LastChecked/LastChanged outside the monitor loop.
+func stampStatusTimes(curr []Service, now time.Time) {
+	lastChanged.RLock()
+	defer lastChanged.RUnlock()
+	for i := range curr {
+		curr[i].LastChecked = now
+		curr[i].LastChanged = lastChanged.at[statusKey(curr[i])]
 	}
 }
 
@@ -177,7 +334,7 @@ func defaultServicesFromInfo(infos []ServiceInfo) []Service {
 		if unit == "" {
 			unit = s.SystemdName
 		}
-		isSystemd := runtime.GOOS == "linux" && unit != ""
+		isSystemd := runtime.GOOS == "linux" && unit != "" && s.Type == ""
 		res = append(res, Service{
 			Port:         s.Port,
 			Name:         s.Name,
@@ -187,9 +344,12 @@ func defaultServicesFromInfo(infos []ServiceInfo) []Service {
 			SystemdName:  unit,
 			IsSystemd:    isSystemd,
 			Active:       false,
+			State:        "down",
 			Controls:     s.Controls,
 			ControlsRun:  s.ControlsRun,
 			ControlsShut: s.ControlsShut,
+			Type:         s.Type,
+			Tags:         s.Tags,
 		})
 	}
 	return res
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// statusSchemaVersion is bumped on incompatible changes of StatusDocument.
+const statusSchemaVersion = 2
+
+// version is the monitor build, set with -ldflags "-X main.version=...".
+var version = "dev"
+
+// StatusDocument is the exported status.json (EXPORT_FORMAT=v2, default).
+// EXPORT_FORMAT=legacy writes the bare []Service array instead.
+type StatusDocument struct {
+	SchemaVersion   int           `json:"schema_version"`
+	GeneratedAt     time.Time     `json:"generated_at"`
+	Monitor         StatusMonitor `json:"monitor"`
+	IntervalSeconds float64       `json:"interval_seconds"`
+	Services        []StatusEntry `json:"services"`
+	// Stale is only set when /status.json is served past its freshness limit.
+	Stale bool `json:"stale,omitempty"`
+}
+
+type StatusMonitor struct {
+	Host    string `json:"host"`
+	Version string `json:"version"`
+}
+
+// StatusEntry is one service in a StatusDocument.
+type StatusEntry struct {
+	Name         string             `json:"name"`
+	State        string             `json:"state"` // up, down, degraded or maintenance
+	Active       bool               `json:"active"`
+	Type         string             `json:"type,omitempty"`
+	Reason       string             `json:"reason,omitempty"`
+	Detail       string             `json:"detail,omitempty"`
+	LatencyMs    float64            `json:"latency_ms,omitempty"`
+	LastChecked  time.Time          `json:"last_checked,omitzero"`
+	LastChanged  time.Time          `json:"last_changed,omitzero"`
+	Uptime       map[string]float64 `json:"uptime,omitempty"`
+	Maintenance  string             `json:"maintenance,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	Port         int                `json:"port,omitempty"`
+	ShowPort     bool               `json:"show_port,omitempty"`
+	Link         string             `json:"link,omitempty"`
+	Image        string             `json:"image,omitempty"`
+	SystemdName  string             `json:"systemd_name,omitempty"`
+	IsSystemd    bool               `json:"is_systemd,omitempty"`
+	Controls     bool               `json:"controls,omitempty"`
+	ControlsRun  bool               `json:"controls_run,omitempty"`
+	ControlsShut bool               `json:"controls_shut,omitempty"`
+}
+
+func statusEntryFrom(s Service) StatusEntry {
+	return StatusEntry{
+		Name: s.Name, State: s.State, Active: s.Active, Type: s.Type,
+		Reason: s.Reason, Detail: s.Detail, LatencyMs: s.LatencyMs,
+		LastChecked: s.LastChecked, LastChanged: s.LastChanged,
+		Uptime: s.Uptime, Maintenance: s.Maintenance, Tags: s.Tags,
+		Port: s.Port, ShowPort: s.ShowPort, Link: s.Link, Image: s.Image,
+		SystemdName: s.SystemdName, IsSystemd: s.IsSystemd,
+		Controls: s.Controls, ControlsRun: s.ControlsRun, ControlsShut: s.ControlsShut,
+	}
+}
+
+func (e StatusEntry) service() Service {
+	return Service{
+		Name: e.Name, State: e.State, Active: e.Active, Type: e.Type,
+		Reason: e.Reason, Detail: e.Detail, LatencyMs: e.LatencyMs,
+		LastChecked: e.LastChecked, LastChanged: e.LastChanged,
+		Uptime: e.Uptime, Maintenance: e.Maintenance, Tags: e.Tags,
+		Port: e.Port, ShowPort: e.ShowPort, Link: e.Link, Image: e.Image,
+		SystemdName: e.SystemdName, IsSystemd: e.IsSystemd,
+		Controls: e.Controls, ControlsRun: e.ControlsRun, ControlsShut: e.ControlsShut,
+	}
+}
+
+var monitorHost = func() string {
+	h, _ := os.Hostname()
+	return h
+}()
+
+func newStatusDocument(status []Service, now time.Time) StatusDocument {
+	doc := StatusDocument{
+		SchemaVersion:   statusSchemaVersion,
+		GeneratedAt:     now,
+		Monitor:         StatusMonitor{Host: monitorHost, Version: version},
+		IntervalSeconds: statusExportInterval.Seconds(),
+		Services:        make([]StatusEntry, 0, len(status)),
+	}
+	for _, s := range status {
+		doc.Services = append(doc.Services, statusEntryFrom(s))
+	}
+	return doc
+}
+
+// parseStatusDocument accepts both the v2 document and the legacy array;
+// a legacy file yields a document without GeneratedAt/Monitor.
+func parseStatusDocument(data []byte) (StatusDocument, error) {
+	var doc StatusDocument
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '[' {
+		var legacy []Service
+		if err := json.Unmarshal(t, &legacy); err != nil {
+			return doc, err
+		}
+		for _, s := range legacy {
+			if s.State == "" {
+				s.State = serviceState(s.Active, false)
+			}
+			doc.Services = append(doc.Services, statusEntryFrom(s))
+		}
+		return doc, nil
+	}
+	err := json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+func (d StatusDocument) services() []Service {
+	res := make([]Service, 0, len(d.Services))
+	for _, e := range d.Services {
+		res = append(res, e.service())
+	}
+	return res
+}
+
+// importedStatus is a status file read for rendering, with its freshness.
+type importedStatus struct {
+	Services    []Service
+	GeneratedAt time.Time     // from the document, else the file mtime
+	Interval    time.Duration // exporter interval (document or local)
+	Stale       bool
+}
+
+// staleAfter is how long an import may go without updates before it is
+// reported as stale.
+func staleAfter(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * envCfg.StaleFactor)
+}
+
+// readImportedStatus loads path and judges it against the exporter's
+// interval multiplied by STALE_FACTOR.
+func readImportedStatus(path string, now time.Time) (importedStatus, error) {
+	var imp importedStatus
+	fi, err := os.Stat(path)
+	if err != nil {
+		return imp, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return imp, err
+	}
+	doc, err := parseStatusDocument(data)
+	if err != nil {
+		return imp, err
+	}
+	imp.Services = doc.services()
+	imp.GeneratedAt = doc.GeneratedAt
+	if imp.GeneratedAt.IsZero() {
+		imp.GeneratedAt = fi.ModTime()
+	}
+	imp.Interval = statusExportInterval
+	if doc.IntervalSeconds > 0 {
+		imp.Interval = time.Duration(doc.IntervalSeconds * float64(time.Second))
+	}
+	imp.Stale = now.Sub(imp.GeneratedAt) > staleAfter(imp.Interval)
+	return imp, nil
+}
+
+// staleBanner explains outdated or missing data on the page ("" = fresh).
+func (imp importedStatus) staleBanner(now time.Time) string {
+	if !imp.Stale {
+		return ""
+	}
+	return fmt.Sprintf("Data is outdated: the status file was last updated %s ago (%s), the exporter runs every %s. States below may no longer be accurate.",
+		formatDurationSec(int64(now.Sub(imp.GeneratedAt).Seconds())), imp.GeneratedAt.Local().Format("02 Jan 15:04:05"), imp.Interval)
+}
+
+// serveStatusJSON serves the exported file; past the freshness limit it
+// adds X-Status-Stale and, for the v2 document, "stale": true.
+func serveStatusJSON(w http.ResponseWriter, r *http.Request, path string, infos []ServiceInfo) {
+	imp, err := readImportedStatus(path, time.Now())
+	hide := hasPrivateServices(infos) && !privateVisible(r)
+	if err != nil && !hide || err == nil && !imp.Stale && !hide {
+		serveStatic(w, r, path)
+		return
+	}
+	if imp.Stale {
+		w.Header().Set("X-Status-Stale", "true")
+		w.Header().Set("X-Status-Generated-At", imp.GeneratedAt.UTC().Format(time.RFC3339))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	parsed := false
+	switch t := bytes.TrimSpace(data); {
+	case len(t) > 0 && t[0] == '{':
+		var doc StatusDocument
+		if json.Unmarshal(t, &doc) == nil {
+			doc.Stale = doc.Stale || imp.Stale
+			if hide {
+				entries := make([]StatusEntry, 0, len(doc.Services))
+				for _, e := range doc.Services {
+					if !isPrivateService(infos, e.Name) {
+						entries = append(entries, e)
+					}
+				}
+				doc.Services = entries
+			}
+			if b, err := json.MarshalIndent(doc, "", "  "); err == nil {
+				data, parsed = b, true
+			}
+		}
+	case hide && len(t) > 0 && t[0] == '[':
+		var legacy []Service
+		if json.Unmarshal(t, &legacy) == nil {
+			if b, err := json.MarshalIndent(publicServices(legacy, infos), "", "  "); err == nil {
+				data, parsed = b, true
+			}
+		}
+	}
+	if hide && !parsed {
+		// never fall back to the unfiltered file
+		http.Error(w, "status file is unreadable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -1,25 +1,46 @@
 package main
 
+import "time"
+
 // Config represents the configuration structure
 // JSON-loaded from config.json
 // Optional fields kept for backward compatibility
 // and to avoid breaking existing deployments.
 type Config struct {
-	Port                int    `json:"port"`
-	ServicesFile        string `json:"services_file,omitempty"`
-	WebDir              string `json:"web_dir,omitempty"`
-	TemplateFile        string `json:"template_file,omitempty"`
-	AdminLogin          string `json:"admin_login,omitempty"`
-	AdminPass           string `json:"admin_password,omitempty"`
-	LogFile             string `json:"log_file,omitempty"`
-	LogMaxBytes         int    `json:"log_max_bytes,omitempty"`
-	CommonPasswordsFile string `json:"common_passwords_file,omitempty"`
+	Port                 int    `json:"port"`
+	ServicesFile         string `json:"services_file,omitempty"`
+	WebDir               string `json:"web_dir,omitempty"`
+	TemplateFile         string `json:"template_file,omitempty"`
+	AdminLogin           string `json:"admin_login,omitempty"`
+	AdminPass            string `json:"admin_password,omitempty"`
+	LogFile              string `json:"log_file,omitempty"`
+	LogMaxBytes          int    `json:"log_max_bytes,omitempty"`
+	CommonPasswordsFile  string `json:"common_passwords_file,omitempty"`
+	HistoryDir           string `json:"history_dir,omitempty"`
+	HistoryRetentionDays int    `json:"history_retention_days,omitempty"`
+	IncidentsFile        string `json:"incidents_file,omitempty"`
+	MaintenanceFile      string `json:"maintenance_file,omitempty"`
+	MetricsToken         string `json:"metrics_token,omitempty"`
+	// Peers are other monitors merged into this dashboard.
+	Peers []PeerConfig `json:"peers,omitempty"`
+	// Agent pushes this instance's status to a server; Agents are the
+	// agents a server accepts.
+	Agent  *AgentConfig `json:"agent,omitempty"`
+	Agents []AgentKey   `json:"agents,omitempty"`
+	// Notifiers receive status change events.
+	Notifiers       []NotifierConfig `json:"notifiers,omitempty"`
+	DeliveryLogFile string           `json:"delivery_log_file,omitempty"`
+	// AlertPolicies repeat and escalate unacknowledged down alerts.
+	AlertPolicies []AlertPolicy `json:"alert_policies,omitempty"`
+	// Routes send events of some services / severities to some notifiers.
+	Routes []RouteRule `json:"routes,omitempty"`
 }
 
 // ServicesConfig represents the services configuration
 // JSON-loaded from services.json
 type ServicesConfig struct {
-	Services []ServiceInfo `json:"services"`
+	Services    []ServiceInfo       `json:"services"`
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty"`
 }
 
 // ServiceInfo represents information about a service
@@ -38,6 +59,70 @@ type ServiceInfo struct {
 	ControlsShut bool              `json:"controls_shut,omitempty"`
 	RunPath      string            `json:"run_path,omitempty"`
 	RunEnv       map[string]string `json:"run_env,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	// Private hides the service from anonymous badge and feed requests.
+	Private bool `json:"private,omitempty"`
+	// Critical services alert even during a notifier's quiet hours.
+	Critical bool `json:"critical,omitempty"`
+	// Type selects a dedicated check instead of the systemd/port probe.
+	// Empty keeps the legacy behaviour.
+	Type string     `json:"type,omitempty"`
+	HTTP *HTTPCheck `json:"http,omitempty"`
+	Ping *PingCheck `json:"ping,omitempty"`
+	File *FileCheck `json:"file,omitempty"`
+	Cert *CertCheck `json:"cert,omitempty"`
+}
+
+// HTTPCheck describes a scripted sequence of HTTP requests sharing
+// one cookie jar. Values captured by a step are available to later
+// steps as ${name} in url, headers, body and expect_contains.
+type HTTPCheck struct {
+	Timeout string     `json:"timeout,omitempty"`
+	Steps   []HTTPStep `json:"steps"`
+}
+
+// HTTPStep is a single request of an HTTPCheck.
+// Capture maps a variable name to a regexp applied to the response body;
+// the first submatch (or whole match) is stored.
+type HTTPStep struct {
+	Name           string            `json:"name,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	ExpectStatus   int               `json:"expect_status,omitempty"`
+	ExpectContains string            `json:"expect_contains,omitempty"`
+	Capture        map[string]string `json:"capture,omitempty"`
+}
+
+// PingCheck sends Count ICMP echo probes to Host.
+// The service is down when loss exceeds MaxLoss percent
+// (unset = 100: down only when nothing answers; 0 = any loss).
+type PingCheck struct {
+	Host     string   `json:"host"`
+	Count    int      `json:"count,omitempty"`
+	Interval string   `json:"interval,omitempty"`
+	Timeout  string   `json:"timeout,omitempty"`
+	MaxLoss  *float64 `json:"max_loss,omitempty"`
+}
+
+// FileCheck watches files matching Path (a file or glob).
+// The newest match must be younger than MaxAge and at least MinSize bytes;
+// ExpectCount, when set, is the minimum number of matching files.
+type FileCheck struct {
+	Path        string `json:"path"`
+	MaxAge      string `json:"max_age,omitempty"`
+	MinSize     int64  `json:"min_size,omitempty"`
+	ExpectCount int    `json:"expect_count,omitempty"`
+}
+
+// CertCheck reads PEM certificates (chains included) from Paths (files or globs)
+// and reports the earliest expiry. Below WarnDays the service is degraded,
+// below CriticalDays (or expired) it is down.
+type CertCheck struct {
+	Paths        []string `json:"paths"`
+	WarnDays     int      `json:"warn_days,omitempty"`
+	CriticalDays int      `json:"critical_days,omitempty"`
 }
 
 // Service is the rendered status entry for the UI.
@@ -50,7 +135,17 @@ type Service struct {
 	SystemdName  string
 	IsSystemd    bool
 	Active       bool
+	State        string // up, down, degraded or maintenance
 	Controls     bool
 	ControlsRun  bool
 	ControlsShut bool
+	Type         string
+	Reason       string
+	Detail       string
+	LatencyMs    float64
+	Uptime       map[string]float64 `json:",omitempty"` // window label (24h, 7d, ...) -> percent
+	Tags         []string           `json:",omitempty"`
+	Maintenance  string             `json:",omitempty"` // active window summary
+	LastChecked  time.Time          `json:",omitzero"`
+	LastChanged  time.Time          `json:",omitzero"`
 }